@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"main/packages/alertmanager"
 	"main/packages/config"
+	"main/packages/database"
 	"main/packages/kubernetes"
 	"main/packages/utils"
 	"net/http"
@@ -17,20 +19,65 @@ func main() {
 	port := config.GetEnv("PORT", "5000")
 	token := config.GetEnv("AUTH_TOKEN", "secret")
 
-	if err := kubernetes.InitKubernetesClients(); err != nil {
-		log.Fatalf("Failed to initialize Kubernetes clients: %v", err)
+	kubeContext := flag.String("kube-context", config.GetEnv("KUBE_CONTEXT", ""), "kubeconfig context to use instead of the in-cluster configuration")
+	kubernetesMode := flag.String("kubernetes", config.GetEnv("KUBERNETES_MODE", "required"), "required, optional or disabled; without Kubernetes its routes return 503")
+	flag.Parse()
+
+	switch *kubernetesMode {
+	case "required", "optional":
+		if err := kubernetes.InitKubernetesClients(*kubeContext); err != nil {
+			if *kubernetesMode == "required" {
+				log.Fatalf("Failed to initialize Kubernetes clients: %v", err)
+			}
+			log.Printf("Starting without Kubernetes, its routes return 503: %v", err)
+		}
+	case "disabled":
+		log.Printf("Kubernetes is disabled, its routes return 503")
+	default:
+		log.Fatalf("Unknown Kubernetes mode %q, expected required, optional or disabled", *kubernetesMode)
+	}
+
+	if config.GetEnv("KUBERNETES_CACHE", "true") == "true" {
+		kubernetes.StartInformerCaches()
+	}
+
+	log.Println("Initializing Doris client...")
+	dorisClient, err := database.NewDorisClient()
+	if err != nil {
+		log.Fatalf("Failed to initialize Doris client: %v", err)
+	}
+	if err := alertmanager.InitStore(dorisClient); err != nil {
+		log.Fatalf("Failed to initialize alert store: %v", err)
+	}
+	if err := kubernetes.InitStore(dorisClient); err != nil {
+		log.Fatalf("Failed to initialize Kubernetes store: %v", err)
 	}
+	log.Println("Doris client initialized successfully")
 
 	router := http.NewServeMux()
 
-	router.Handle("GET /pods", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.PodsGETHandler), token)))
-	router.Handle("GET /namespaces", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.NamespacesGETHandler), token)))
-	router.Handle("GET /nodes", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.NodesGETHandler), token)))
-	router.Handle("GET /deployments", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.DeploymentsGETHandler), token)))
+	router.Handle("GET /readyz", http.HandlerFunc(kubernetes.ReadinessGETHandler))
+	router.Handle("GET /clusters", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.ClustersGETHandler), token)))
+	router.Handle("GET /pods", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.PodsGETHandler)), token)))
+	router.Handle("GET /namespaces", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.NamespacesGETHandler)), token)))
+	router.Handle("GET /nodes", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.NodesGETHandler)), token)))
+	router.Handle("GET /deployments", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.DeploymentsGETHandler)), token)))
+	router.Handle("GET /resources", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.ResourceKindsGETHandler), token)))
+	router.Handle("GET /resources/{resource}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.ResourcesGETHandler)), token)))
+	router.Handle("GET /resources/{resource}/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.ResourceGETHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/pods/{pod}/logs", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.PodLogsGETHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/{kind}/{name}/restart", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.RestartWorkloadHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/{kind}/{name}/scale", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.ScaleWorkloadHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/deployments/{name}/rollback", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.RollbackDeploymentHandler)), token)))
+	router.Handle("GET /audit/actions", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.WorkloadActionsGETHandler), token)))
+	router.Handle("GET /events", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.EventsGETHandler)), token)))
+	router.Handle("GET /watch/{kind}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.WatchHandler)), token)))
 
 	router.Handle("GET /alerts", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.AlertGETHandler), token)))
 	router.Handle("POST /alerts", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.AlertPOSTHandler), token)))
 
+	router.Handle("GET /alerts/{fingerprint}/events", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.AlertEventsGETHandler)), token)))
+	router.Handle("GET /alerts/{fingerprint}/logs", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.AlertLogsGETHandler)), token)))
 	router.Handle("GET /alerts/firing", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.AlertFiringGETHandler), token)))
 
 	router.Handle("GET /silences", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.SilencesGETHandler), token)))
@@ -38,10 +85,61 @@ func main() {
 	router.Handle("POST /alerts/silences", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.AlertSilencesPOSTHandler), token)))
 	router.Handle("DELETE /alerts/silences/{id}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.AlertSilencesDELETEHandler), token)))
 
-	router.Handle("GET /rules", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.GetRulesHandler), token)))
-	router.Handle("POST /rules", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.CreateRuleHandler), token)))
-	router.Handle("PUT /rules/{id}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.UpdateRuleHandler), token)))
-	router.Handle("DELETE /rules/{id}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.DeleteRuleHandler), token)))
+	router.Handle("GET /alertmanager/groups", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.AlertGroupsGETHandler), token)))
+	router.Handle("GET /alertmanager/receivers", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.ReceiversGETHandler), token)))
+	router.Handle("GET /alertmanager/status", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.AlertmanagerStatusGETHandler), token)))
+	router.Handle("POST /alertmanager/routes/test", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(alertmanager.RouteTestPOSTHandler), token)))
+
+	router.Handle("GET /rules", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.GetRulesHandler)), token)))
+	router.Handle("POST /rules", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.CreateRuleHandler)), token)))
+	router.Handle("GET /rules/export", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.ExportRulesHandler)), token)))
+	router.Handle("POST /rules/import", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.ImportRulesHandler)), token)))
+	router.Handle("POST /rules/validate", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.ValidateRuleHandler), token)))
+	router.Handle("POST /rules/test", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.TestRuleHandler), token)))
+	router.Handle("POST /rules/backtest", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.BacktestRuleHandler), token)))
+	router.Handle("PUT /rules/{id}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.UpdateRuleHandler)), token)))
+	router.Handle("DELETE /rules/{id}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.DeleteRuleHandler)), token)))
+
+	router.Handle("GET /namespaces/{ns}/rules", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.ListNamespacedRulesHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/rules", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.CreateNamespacedRuleHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/rules/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.GetNamespacedRuleHandler)), token)))
+	router.Handle("PUT /namespaces/{ns}/rules/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.UpdateNamespacedRuleHandler)), token)))
+	router.Handle("PATCH /namespaces/{ns}/rules/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.PatchNamespacedRuleHandler)), token)))
+	router.Handle("DELETE /namespaces/{ns}/rules/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.DeleteNamespacedRuleHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/rules/{name}/revisions", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.RuleRevisionsGETHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/rules/{name}/revisions/diff", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.RuleRevisionsDiffGETHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/rules/{name}/revisions/{revision}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.RuleRevisionGETHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/rules/{name}/rollback", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.RollbackRuleHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/rules/{name}/groups/{group}/alerts", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.AddAlertingRuleHandler)), token)))
+	router.Handle("PUT /namespaces/{ns}/rules/{name}/groups/{group}/alerts/{alert}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.UpdateAlertingRuleHandler)), token)))
+	router.Handle("DELETE /namespaces/{ns}/rules/{name}/groups/{group}/alerts/{alert}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.DeleteAlertingRuleHandler)), token)))
+
+	router.Handle("GET /rule-templates", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.RuleTemplatesGETHandler), token)))
+	router.Handle("GET /rule-templates/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.RuleTemplateGETHandler), token)))
+	router.Handle("POST /rule-templates/{name}/render", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.RenderRuleTemplateHandler), token)))
+
+	router.Handle("GET /slos", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.SLOsGETHandler)), token)))
+	router.Handle("POST /slos", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.CreateSLOHandler)), token)))
+	router.Handle("GET /slos/{ns}/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.SLOGETHandler)), token)))
+	router.Handle("PUT /slos/{ns}/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.UpdateSLOHandler)), token)))
+	router.Handle("DELETE /slos/{ns}/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.DeleteSLOHandler)), token)))
+
+	router.Handle("GET /monitors/{kind}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.MonitorsGETHandler)), token)))
+	router.Handle("POST /monitors/{kind}/validate", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.ValidateMonitorHandler), token)))
+	router.Handle("GET /namespaces/{ns}/monitors/{kind}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.NamespacedMonitorsGETHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/monitors/{kind}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.CreateMonitorHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/monitors/{kind}/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.MonitorGETHandler)), token)))
+	router.Handle("PUT /namespaces/{ns}/monitors/{kind}/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.UpdateMonitorHandler)), token)))
+	router.Handle("DELETE /namespaces/{ns}/monitors/{kind}/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.DeleteMonitorHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/monitors/{kind}/{name}/check", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.MonitorCheckGETHandler)), token)))
+
+	router.Handle("GET /alertmanagerconfigs", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.AlertmanagerConfigsGETHandler)), token)))
+	router.Handle("POST /alertmanagerconfigs/validate", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(kubernetes.ValidateAlertmanagerConfigHandler), token)))
+	router.Handle("GET /namespaces/{ns}/alertmanagerconfigs", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.NamespacedAlertmanagerConfigsGETHandler)), token)))
+	router.Handle("POST /namespaces/{ns}/alertmanagerconfigs", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.CreateAlertmanagerConfigHandler)), token)))
+	router.Handle("GET /namespaces/{ns}/alertmanagerconfigs/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.AlertmanagerConfigGETHandler)), token)))
+	router.Handle("PUT /namespaces/{ns}/alertmanagerconfigs/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.UpdateAlertmanagerConfigHandler)), token)))
+	router.Handle("DELETE /namespaces/{ns}/alertmanagerconfigs/{name}", utils.LoggingMiddleware(utils.AuthenticationMiddleware(kubernetes.RequireClients(http.HandlerFunc(kubernetes.DeleteAlertmanagerConfigHandler)), token)))
 
 	// Add the new PromQL validation route
 	router.Handle("POST /validate/promql", utils.LoggingMiddleware(utils.AuthenticationMiddleware(http.HandlerFunc(utils.PromQLValidationHandler), token)))
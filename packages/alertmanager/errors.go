@@ -8,4 +8,7 @@ var (
 	ErrorJSONEncoding       = fmt.Errorf("JSON encoding error")
 	ErrorFailedToRetrieve   = fmt.Errorf("failed to retrieve alerts")
 	ErrorSilenceIDNotFound  = fmt.Errorf("silence ID is required")
+
+	ErrorInvalidAlertmanagerConfig = fmt.Errorf("invalid Alertmanager configuration")
+	ErrorLabelsRequired            = fmt.Errorf("at least one label is required")
 )
@@ -2,6 +2,7 @@ package alertmanager
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"main/packages/database"
@@ -13,23 +14,13 @@ import (
 
 var dorisClient *database.DorisClient
 
-func init() {
-	log.Println("Initializing Doris client...")
-
-	client, err := database.NewDorisClient()
-	if err != nil {
-		log.Fatalf("Failed to initialize Doris client: %v", err)
-	}
-
-	log.Println("Doris client initialized successfully")
-
-	err = client.CreateTableIfNotExists()
-	if err != nil {
-		log.Fatalf("Failed to create table: %v", err)
+// InitStore keeps the Doris client alerts are stored with, creating the alerts table when it does not exist
+func InitStore(client *database.DorisClient) error {
+	if err := client.CreateTableIfNotExists(); err != nil {
+		return fmt.Errorf("failed to create table: %v", err)
 	}
-
 	dorisClient = client
-	log.Println("Doris client initialized successfully")
+	return nil
 }
 
 // AlertPOSTHandler processes incoming alert requests.
@@ -171,3 +162,82 @@ func AlertSilencesDELETEHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
+
+// GET /alertmanager/groups
+// AlertGroupsGETHandler returns the alert groups as grouped and routed by Alertmanager
+func AlertGroupsGETHandler(w http.ResponseWriter, r *http.Request) {
+	groups, err := FetchAlertGroups()
+	if err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Error fetching alert groups: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	// Optionally narrow the groups down to a single receiver
+	if receiver := r.URL.Query().Get("receiver"); receiver != "" {
+		filtered := make([]models.AlertGroup, 0, len(groups))
+		for _, group := range groups {
+			if group.Receiver == receiver {
+				filtered = append(filtered, group)
+			}
+		}
+		groups = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// GET /alertmanager/receivers
+// ReceiversGETHandler returns the receivers configured in Alertmanager
+func ReceiversGETHandler(w http.ResponseWriter, r *http.Request) {
+	receivers, err := FetchReceivers()
+	if err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Error fetching receivers: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receivers)
+}
+
+// GET /alertmanager/status
+// AlertmanagerStatusGETHandler returns the cluster health, version and loaded configuration of Alertmanager
+func AlertmanagerStatusGETHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := FetchAlertmanagerStatus()
+	if err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Error fetching Alertmanager status: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// POST /alertmanager/routes/test
+// RouteTestPOSTHandler returns the routes, receivers and timers a label set would be dispatched with
+func RouteTestPOSTHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.RouteTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteJSONError(w, ErrorInvalidJSONPayload.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(request.Labels) == 0 {
+		utils.WriteJSONError(w, ErrorLabelsRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := TestRoute(request.Labels, request.Config)
+	if err != nil {
+		code := http.StatusBadGateway
+		if errors.Is(err, ErrorInvalidAlertmanagerConfig) {
+			code = http.StatusUnprocessableEntity
+		}
+		utils.WriteJSONError(w, fmt.Sprintf("Error testing route: %v", err), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
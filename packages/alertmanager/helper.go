@@ -10,6 +10,12 @@ import (
 	"main/packages/models"
 	"net/http"
 	"regexp"
+	"sort"
+	"time"
+
+	amconfig "github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/common/model"
 )
 
 var prometheusUrl = config.GetEnv("PROMETHEUS_URL", "http://localhost:9090")
@@ -67,6 +73,195 @@ func FetchSilencedAlerts() ([]models.Silence, error) {
 	return result.Data, nil
 }
 
+// fetchAlertmanagerV2 performs a GET against the Alertmanager v2 API and decodes the response into out.
+func fetchAlertmanagerV2(path string, out interface{}) error {
+	resp, err := http.Get(alertmanagerUrl + "/api/v2" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+type gettableAlert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	Receivers   []models.Receiver `json:"receivers"`
+	Status      struct {
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+	StartsAt     time.Time `json:"startsAt"`
+	EndsAt       time.Time `json:"endsAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	GeneratorURL string    `json:"generatorURL"`
+}
+
+func FetchAlertGroups() ([]models.AlertGroup, error) {
+	var result []struct {
+		Labels   map[string]string `json:"labels"`
+		Receiver models.Receiver   `json:"receiver"`
+		Alerts   []gettableAlert   `json:"alerts"`
+	}
+	if err := fetchAlertmanagerV2("/alerts/groups", &result); err != nil {
+		return nil, err
+	}
+
+	groups := make([]models.AlertGroup, 0, len(result))
+	for _, g := range result {
+		group := models.AlertGroup{
+			Receiver: g.Receiver.Name,
+			Labels:   g.Labels,
+			Alerts:   make([]models.AlertmanagerAlert, 0, len(g.Alerts)),
+		}
+		for _, a := range g.Alerts {
+			receivers := make([]string, 0, len(a.Receivers))
+			for _, r := range a.Receivers {
+				receivers = append(receivers, r.Name)
+			}
+			group.Alerts = append(group.Alerts, models.AlertmanagerAlert{
+				Fingerprint:  a.Fingerprint,
+				State:        a.Status.State,
+				Labels:       a.Labels,
+				Annotations:  a.Annotations,
+				Receivers:    receivers,
+				SilencedBy:   a.Status.SilencedBy,
+				InhibitedBy:  a.Status.InhibitedBy,
+				StartsAt:     a.StartsAt,
+				EndsAt:       a.EndsAt,
+				UpdatedAt:    a.UpdatedAt,
+				GeneratorURL: a.GeneratorURL,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func FetchReceivers() ([]models.Receiver, error) {
+	var receivers []models.Receiver
+	if err := fetchAlertmanagerV2("/receivers", &receivers); err != nil {
+		return nil, err
+	}
+	return receivers, nil
+}
+
+func FetchAlertmanagerStatus() (*models.AlertmanagerStatus, error) {
+	var result struct {
+		Cluster struct {
+			Name   string               `json:"name"`
+			Status string               `json:"status"`
+			Peers  []models.ClusterPeer `json:"peers"`
+		} `json:"cluster"`
+		Config struct {
+			Original string `json:"original"`
+		} `json:"config"`
+		Uptime      time.Time          `json:"uptime"`
+		VersionInfo models.VersionInfo `json:"versionInfo"`
+	}
+	if err := fetchAlertmanagerV2("/status", &result); err != nil {
+		return nil, err
+	}
+
+	return &models.AlertmanagerStatus{
+		// "disabled" means Alertmanager runs without clustering, which is healthy for a single replica
+		Healthy:       result.Cluster.Status == "ready" || result.Cluster.Status == "disabled",
+		ClusterName:   result.Cluster.Name,
+		ClusterStatus: result.Cluster.Status,
+		Peers:         result.Cluster.Peers,
+		VersionInfo:   result.VersionInfo,
+		Uptime:        result.Uptime,
+		Config:        result.Config.Original,
+	}, nil
+}
+
+// TestRoute evaluates a label set against the Alertmanager routing tree and returns every matching route.
+// When rawConfig is empty the configuration currently loaded by Alertmanager is used.
+func TestRoute(labels map[string]string, rawConfig string) (*models.RouteTestResponse, error) {
+	if rawConfig == "" {
+		status, err := FetchAlertmanagerStatus()
+		if err != nil {
+			return nil, err
+		}
+		rawConfig = status.Config
+	}
+
+	cfg, err := amconfig.Load(rawConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidAlertmanagerConfig, err)
+	}
+
+	lset := make(model.LabelSet, len(labels))
+	for name, value := range labels {
+		lset[model.LabelName(name)] = model.LabelValue(value)
+	}
+
+	root := dispatch.NewRoute(cfg.Route, nil)
+	response := &models.RouteTestResponse{
+		Labels:    labels,
+		Receivers: []string{},
+		Matches:   []models.RouteMatch{},
+	}
+	seen := map[string]bool{}
+	for _, route := range root.Match(lset) {
+		response.Matches = append(response.Matches, newRouteMatch(route, routePath(root, route)))
+		if !seen[route.RouteOpts.Receiver] {
+			seen[route.RouteOpts.Receiver] = true
+			response.Receivers = append(response.Receivers, route.RouteOpts.Receiver)
+		}
+	}
+	return response, nil
+}
+
+func newRouteMatch(route *dispatch.Route, path []*dispatch.Route) models.RouteMatch {
+	opts := route.RouteOpts
+	match := models.RouteMatch{
+		Receiver:            opts.Receiver,
+		Path:                make([]string, 0, len(path)),
+		GroupBy:             make([]string, 0, len(opts.GroupBy)),
+		GroupByAll:          opts.GroupByAll,
+		GroupWait:           model.Duration(opts.GroupWait).String(),
+		GroupInterval:       model.Duration(opts.GroupInterval).String(),
+		RepeatInterval:      model.Duration(opts.RepeatInterval).String(),
+		Continue:            route.Continue,
+		MuteTimeIntervals:   opts.MuteTimeIntervals,
+		ActiveTimeIntervals: opts.ActiveTimeIntervals,
+	}
+	for _, node := range path {
+		match.Path = append(match.Path, node.Matchers.String())
+	}
+	for name := range opts.GroupBy {
+		match.GroupBy = append(match.GroupBy, string(name))
+	}
+	sort.Strings(match.GroupBy)
+	return match
+}
+
+// routePath returns the chain of routes from root down to target, both included.
+func routePath(root, target *dispatch.Route) []*dispatch.Route {
+	if root == target {
+		return []*dispatch.Route{root}
+	}
+	for _, child := range root.Routes {
+		if path := routePath(child, target); path != nil {
+			return append([]*dispatch.Route{root}, path...)
+		}
+	}
+	return nil
+}
+
 func IsSilenced(alert models.AlertPrometheus, silences []models.Silence) bool {
 	for _, silence := range silences {
 		if silence.Status.State == "active" {
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"main/packages/models"
+	"strings"
+	"time"
+)
+
+func (c *DorisClient) CreateWorkloadActionsTableIfNotExists() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS workload_actions (
+			created_at DATETIME NOT NULL,
+			cluster VARCHAR(253) NOT NULL,
+			namespace VARCHAR(253) NOT NULL,
+			kind VARCHAR(63) NOT NULL,
+			name VARCHAR(253) NOT NULL,
+			action VARCHAR(63) NOT NULL,
+			author STRING NOT NULL,
+			reason STRING NOT NULL,
+			outcome VARCHAR(31) NOT NULL DEFAULT "succeeded",
+			error STRING,
+			details STRING
+		)
+		DUPLICATE KEY (created_at, cluster, namespace)
+		DISTRIBUTED BY HASH(cluster, namespace, name) BUCKETS 10
+		PROPERTIES (
+			"replication_num" = "1"
+		);
+	`
+
+	_, err := c.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create workload_actions table: %v", err)
+	}
+
+	return nil
+}
+
+// SaveWorkloadAction appends an action on a workload to the audit log
+func (c *DorisClient) SaveWorkloadAction(action *models.WorkloadAction) error {
+	details, err := marshalOptional(action.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal action details: %v", err)
+	}
+
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO workload_actions (
+			created_at,
+			cluster,
+			namespace,
+			kind,
+			name,
+			action,
+			author,
+			reason,
+			outcome,
+			error,
+			details
+		) VALUES ('%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s')
+	`,
+		action.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+		escapeString(action.Cluster),
+		escapeString(action.Namespace),
+		escapeString(action.Kind),
+		escapeString(action.Name),
+		escapeString(action.Action),
+		escapeString(action.Author),
+		escapeString(action.Reason),
+		escapeString(action.Outcome),
+		escapeString(action.Error),
+		escapeString(details),
+	)
+
+	if _, err := c.db.Exec(insertQuery); err != nil {
+		return fmt.Errorf("failed to insert workload action: %v", err)
+	}
+	return nil
+}
+
+// GetWorkloadActions returns the newest entries of the audit log matching the non-empty filters.
+// A non-nil namespaces list restricts the entries to those namespaces.
+func (c *DorisClient) GetWorkloadActions(filters map[string]string, namespaces []string, limit int) ([]models.WorkloadAction, error) {
+	conditions := []string{"1 = 1"}
+	for _, column := range []string{"cluster", "namespace", "kind", "name", "author", "outcome"} {
+		if value := filters[column]; value != "" {
+			conditions = append(conditions, fmt.Sprintf("%s = '%s'", column, escapeString(value)))
+		}
+	}
+	if namespaces != nil {
+		if len(namespaces) == 0 {
+			return []models.WorkloadAction{}, nil
+		}
+		quoted := make([]string, 0, len(namespaces))
+		for _, namespace := range namespaces {
+			quoted = append(quoted, fmt.Sprintf("'%s'", escapeString(namespace)))
+		}
+		conditions = append(conditions, fmt.Sprintf("namespace IN (%s)", strings.Join(quoted, ", ")))
+	}
+	query := fmt.Sprintf(`
+		SELECT created_at, cluster, namespace, kind, name, action, author, reason, outcome, error, details
+		FROM workload_actions
+		WHERE %s
+		ORDER BY created_at DESC
+		LIMIT %d
+	`, strings.Join(conditions, " AND "), limit)
+
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve workload actions: %v", err)
+	}
+	defer rows.Close()
+
+	actions := []models.WorkloadAction{}
+	for rows.Next() {
+		var action models.WorkloadAction
+		var createdAt string
+		var actionError sql.NullString
+		var details []byte
+
+		err := rows.Scan(
+			&createdAt,
+			&action.Cluster,
+			&action.Namespace,
+			&action.Kind,
+			&action.Name,
+			&action.Action,
+			&action.Author,
+			&action.Reason,
+			&action.Outcome,
+			&actionError,
+			&details,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workload action row: %v", err)
+		}
+
+		if action.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAt); err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %v", err)
+		}
+		action.Error = actionError.String
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &action.Details); err != nil {
+				return nil, fmt.Errorf("failed to parse details JSON: %v", err)
+			}
+		}
+
+		actions = append(actions, action)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return actions, nil
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"main/packages/config"
 	"main/packages/models"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -179,6 +180,45 @@ func (c *DorisClient) GetAlerts() ([]models.AlertResponse, error) {
 		SELECT fingerprint, status, alert_name, start_time, end_time, generator_url, labels, annotations
 		FROM alerts
 	`
+	return c.queryAlerts(query)
+}
+
+// GetAlertsByNameInRange returns the alerts with the given name that were active at some point between start and end
+func (c *DorisClient) GetAlertsByNameInRange(name string, start, end time.Time) ([]models.AlertResponse, error) {
+	query := fmt.Sprintf(`
+		SELECT fingerprint, status, alert_name, start_time, end_time, generator_url, labels, annotations
+		FROM alerts
+		WHERE alert_name = '%s'
+			AND start_time <= '%s'
+			AND (end_time IS NULL OR end_time < '1971-01-01 00:00:00' OR end_time >= '%s')
+	`,
+		escapeString(name),
+		end.UTC().Format("2006-01-02 15:04:05"),
+		start.UTC().Format("2006-01-02 15:04:05"),
+	)
+	return c.queryAlerts(query)
+}
+
+// GetAlertByFingerprint returns the alert with the given fingerprint, or nil if it was never received
+func (c *DorisClient) GetAlertByFingerprint(fingerprint string) (*models.AlertResponse, error) {
+	query := fmt.Sprintf(`
+		SELECT fingerprint, status, alert_name, start_time, end_time, generator_url, labels, annotations
+		FROM alerts
+		WHERE fingerprint = '%s'
+	`, escapeString(fingerprint))
+	alerts, err := c.queryAlerts(query)
+	if err != nil || len(alerts) == 0 {
+		return nil, err
+	}
+	return &alerts[0], nil
+}
+
+// escapeString escapes a value for use inside a single-quoted SQL string literal
+func escapeString(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(value)
+}
+
+func (c *DorisClient) queryAlerts(query string) ([]models.AlertResponse, error) {
 	rows, err := c.db.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve alerts: %v", err)
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"main/packages/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (c *DorisClient) CreateRevisionsTableIfNotExists() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS rule_revisions (
+			cluster VARCHAR(253) NOT NULL,
+			namespace VARCHAR(253) NOT NULL,
+			name VARCHAR(253) NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			id VARCHAR(36) NOT NULL,
+			action STRING NOT NULL,
+			author STRING NOT NULL,
+			previous STRING,
+			current STRING,
+			diff STRING
+		)
+		UNIQUE KEY (cluster, namespace, name, created_at, id)
+		DISTRIBUTED BY HASH(cluster, namespace, name) BUCKETS 10
+		PROPERTIES (
+			"replication_num" = "1"
+		);
+	`
+
+	_, err := c.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create rule_revisions table: %v", err)
+	}
+
+	return nil
+}
+
+// revisionTimeFormat keeps the microseconds of created_at, which orders the revisions of a rule
+const revisionTimeFormat = "2006-01-02 15:04:05.000000"
+
+// SaveRuleRevision stores a revision under a random id, so agents writing the same rule never replace each
+// other's rows. Revision numbers are not stored but follow from the order of created_at and id, see numberedRevisions.
+func (c *DorisClient) SaveRuleRevision(revision *models.RuleRevision) error {
+	previous, err := marshalOptional(revision.Previous)
+	if err != nil {
+		return fmt.Errorf("failed to marshal previous object: %v", err)
+	}
+	current, err := marshalOptional(revision.Current)
+	if err != nil {
+		return fmt.Errorf("failed to marshal current object: %v", err)
+	}
+	diff, err := json.Marshal(revision.Diff)
+	if err != nil {
+		return fmt.Errorf("failed to marshal diff: %v", err)
+	}
+
+	revision.ID = uuid.NewString()
+	createdAt := revision.CreatedAt.UTC().Format(revisionTimeFormat)
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO rule_revisions (
+			cluster,
+			namespace,
+			name,
+			created_at,
+			id,
+			action,
+			author,
+			previous,
+			current,
+			diff
+		) VALUES ('%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s', '%s')
+	`,
+		escapeString(revision.Cluster),
+		escapeString(revision.Namespace),
+		escapeString(revision.Name),
+		createdAt,
+		revision.ID,
+		escapeString(revision.Action),
+		escapeString(revision.Author),
+		escapeString(previous),
+		escapeString(current),
+		escapeString(string(diff)),
+	)
+	if _, err := c.db.Exec(insertQuery); err != nil {
+		return fmt.Errorf("failed to insert rule revision: %v", err)
+	}
+
+	positionQuery := fmt.Sprintf(`
+		SELECT COUNT(*) FROM rule_revisions
+		WHERE cluster = '%s' AND namespace = '%s' AND name = '%s'
+		AND (created_at < '%s' OR (created_at = '%s' AND id <= '%s'))
+	`,
+		escapeString(revision.Cluster),
+		escapeString(revision.Namespace),
+		escapeString(revision.Name),
+		createdAt,
+		createdAt,
+		revision.ID,
+	)
+	if err := c.db.QueryRow(positionQuery).Scan(&revision.Revision); err != nil {
+		return fmt.Errorf("failed to number rule revision: %v", err)
+	}
+	return nil
+}
+
+// numberedRevisions selects the revisions of a rule numbered from 1 in the order they were saved
+func numberedRevisions(cluster, namespace, name string) string {
+	return fmt.Sprintf(`
+		SELECT cluster, namespace, name,
+			ROW_NUMBER() OVER (ORDER BY created_at, id) AS revision,
+			id, action, author, created_at, previous, current, diff
+		FROM rule_revisions
+		WHERE cluster = '%s' AND namespace = '%s' AND name = '%s'
+	`,
+		escapeString(cluster),
+		escapeString(namespace),
+		escapeString(name),
+	)
+}
+
+// GetRuleRevisions returns the revisions of a rule, newest first
+func (c *DorisClient) GetRuleRevisions(cluster, namespace, name string) ([]models.RuleRevision, error) {
+	query := fmt.Sprintf("SELECT * FROM (%s) numbered ORDER BY revision DESC", numberedRevisions(cluster, namespace, name))
+	return c.queryRuleRevisions(query)
+}
+
+// GetRuleRevision returns a single revision of a rule, or nil if it does not exist
+func (c *DorisClient) GetRuleRevision(cluster, namespace, name string, revision int) (*models.RuleRevision, error) {
+	query := fmt.Sprintf("SELECT * FROM (%s) numbered WHERE revision = %d", numberedRevisions(cluster, namespace, name), revision)
+	revisions, err := c.queryRuleRevisions(query)
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return &revisions[0], nil
+}
+
+func (c *DorisClient) queryRuleRevisions(query string) ([]models.RuleRevision, error) {
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve rule revisions: %v", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.RuleRevision{}
+	for rows.Next() {
+		var revision models.RuleRevision
+		var createdAt string
+		var previous, current, diff []byte
+
+		err := rows.Scan(
+			&revision.Cluster,
+			&revision.Namespace,
+			&revision.Name,
+			&revision.Revision,
+			&revision.ID,
+			&revision.Action,
+			&revision.Author,
+			&createdAt,
+			&previous,
+			&current,
+			&diff,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rule revision row: %v", err)
+		}
+
+		if revision.CreatedAt, err = time.Parse("2006-01-02 15:04:05.999999", createdAt); err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %v", err)
+		}
+		if len(previous) > 0 {
+			if err := json.Unmarshal(previous, &revision.Previous); err != nil {
+				return nil, fmt.Errorf("failed to parse previous JSON: %v", err)
+			}
+		}
+		if len(current) > 0 {
+			if err := json.Unmarshal(current, &revision.Current); err != nil {
+				return nil, fmt.Errorf("failed to parse current JSON: %v", err)
+			}
+		}
+		if len(diff) > 0 {
+			if err := json.Unmarshal(diff, &revision.Diff); err != nil {
+				return nil, fmt.Errorf("failed to parse diff JSON: %v", err)
+			}
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return revisions, nil
+}
+
+// marshalOptional encodes an object as JSON, or as an empty string when it is nil
+func marshalOptional(object map[string]interface{}) (string, error) {
+	if object == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(object)
+	return string(data), err
+}
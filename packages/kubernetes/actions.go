@@ -0,0 +1,391 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/packages/config"
+	"main/packages/models"
+	"main/packages/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// deploymentRevisionAnnotation numbers the ReplicaSets of a deployment and the deployment itself
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+	defaultAuditLimit = 100
+)
+
+// Outcomes of a workload action in the audit log
+const (
+	actionSucceeded = "succeeded"
+	actionRefused   = "refused"
+	actionFailed    = "failed"
+)
+
+// Replica counts a scale action may set, configured with SCALE_MIN_REPLICAS and SCALE_MAX_REPLICAS.
+// The default minimum of 1 keeps a scale action from taking a workload down.
+var (
+	scaleMinReplicas = parseReplicaLimit("SCALE_MIN_REPLICAS", 1)
+	scaleMaxReplicas = parseReplicaLimit("SCALE_MAX_REPLICAS", 20)
+)
+
+func parseReplicaLimit(key string, defaultValue int32) int32 {
+	value := config.GetEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 0 {
+		log.Printf("Ignoring invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return int32(n)
+}
+
+// workloadKind is a kind the action endpoints can restart and scale
+type workloadKind struct {
+	Kind        string
+	Patch       func(ctx context.Context, c *Cluster, namespace, name string, patch []byte) error
+	GetScale    func(ctx context.Context, c *Cluster, namespace, name string) (*autoscalingv1.Scale, error)
+	UpdateScale func(ctx context.Context, c *Cluster, namespace, name string, scale *autoscalingv1.Scale) error
+}
+
+var workloadKinds = map[string]workloadKind{
+	"deployments": {
+		Kind: "Deployment",
+		Patch: func(ctx context.Context, c *Cluster, namespace, name string, patch []byte) error {
+			_, err := c.clientset.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+			return err
+		},
+		GetScale: func(ctx context.Context, c *Cluster, namespace, name string) (*autoscalingv1.Scale, error) {
+			return c.clientset.AppsV1().Deployments(namespace).GetScale(ctx, name, metav1.GetOptions{})
+		},
+		UpdateScale: func(ctx context.Context, c *Cluster, namespace, name string, scale *autoscalingv1.Scale) error {
+			_, err := c.clientset.AppsV1().Deployments(namespace).UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+			return err
+		},
+	},
+	"statefulsets": {
+		Kind: "StatefulSet",
+		Patch: func(ctx context.Context, c *Cluster, namespace, name string, patch []byte) error {
+			_, err := c.clientset.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+			return err
+		},
+		GetScale: func(ctx context.Context, c *Cluster, namespace, name string) (*autoscalingv1.Scale, error) {
+			return c.clientset.AppsV1().StatefulSets(namespace).GetScale(ctx, name, metav1.GetOptions{})
+		},
+		UpdateScale: func(ctx context.Context, c *Cluster, namespace, name string, scale *autoscalingv1.Scale) error {
+			_, err := c.clientset.AppsV1().StatefulSets(namespace).UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+			return err
+		},
+	},
+}
+
+// RestartWorkload triggers a rolling restart the way kubectl rollout restart does, by stamping the pod template.
+// Paused deployments are refused since the restart would only happen once they are resumed.
+func RestartWorkload(c *Cluster, kind workloadKind, namespace, name string) (map[string]interface{}, error) {
+	if kind.Kind == "Deployment" {
+		deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if deployment.Spec.Paused {
+			return nil, fmt.Errorf("%w: %s/%s", ErrorDeploymentPaused, namespace, name)
+		}
+	}
+
+	restartedAt := time.Now().UTC().Format(time.RFC3339)
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{restartedAtAnnotation: restartedAt},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := kind.Patch(context.TODO(), c, namespace, name, patch); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"restartedAt": restartedAt}, nil
+}
+
+// ScaleWorkload sets the replicas of a workload through its scale subresource, within the configured guardrails.
+// The update carries the resourceVersion it read, so a concurrent change fails with a conflict.
+func ScaleWorkload(c *Cluster, kind workloadKind, namespace, name string, replicas int32) (map[string]interface{}, error) {
+	if replicas < scaleMinReplicas || replicas > scaleMaxReplicas {
+		return nil, fmt.Errorf("%w: %d is outside %d to %d", ErrorReplicasOutOfRange, replicas, scaleMinReplicas, scaleMaxReplicas)
+	}
+
+	scale, err := kind.GetScale(context.TODO(), c, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	from := scale.Spec.Replicas
+	scale.Spec.Replicas = replicas
+	if err := kind.UpdateScale(context.TODO(), c, namespace, name, scale); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"fromReplicas": from, "toReplicas": replicas}, nil
+}
+
+// RollbackDeployment restores the pod template of the ReplicaSet with the highest revision below the current one,
+// like kubectl rollout undo. The deployment controller then records the restored template as a new revision.
+func RollbackDeployment(c *Cluster, namespace, name string) (map[string]interface{}, error) {
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Spec.Paused {
+		return nil, fmt.Errorf("%w: %s/%s", ErrorDeploymentPaused, namespace, name)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := c.clientset.AppsV1().ReplicaSets(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	owned := map[int64]*appsv1.ReplicaSet{}
+	current, _ := strconv.ParseInt(deployment.Annotations[deploymentRevisionAnnotation], 10, 64)
+	for i := range replicaSets.Items {
+		replicaSet := &replicaSets.Items[i]
+		revision, err := strconv.ParseInt(replicaSet.Annotations[deploymentRevisionAnnotation], 10, 64)
+		if err != nil || !metav1.IsControlledBy(replicaSet, deployment) {
+			continue
+		}
+		owned[revision] = replicaSet
+		// Without the annotation on the deployment its newest ReplicaSet is the current one
+		if deployment.Annotations[deploymentRevisionAnnotation] == "" {
+			current = max(current, revision)
+		}
+	}
+
+	var previous int64
+	for revision := range owned {
+		if revision < current && revision > previous {
+			previous = revision
+		}
+	}
+	if previous == 0 {
+		return nil, fmt.Errorf("%w: %s/%s is at revision %d", ErrorNoPreviousRevision, namespace, name, current)
+	}
+
+	template := owned[previous].Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	deployment.Spec.Template = *template
+	if _, err := c.clientset.AppsV1().Deployments(namespace).Update(context.TODO(), deployment, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"fromRevision": current,
+		"toRevision":   previous,
+		"replicaSet":   owned[previous].Name,
+	}, nil
+}
+
+// recordWorkloadAction appends an attempted action to the audit log. Its outcome is already decided,
+// so a failure to record it is logged rather than returned to the caller.
+func recordWorkloadAction(action *models.WorkloadAction) {
+	log.Printf("%s %s %s/%s in cluster %q by %s %s: %s", action.Action, action.Kind, action.Namespace, action.Name, action.Cluster, action.Author, action.Outcome, action.Reason)
+	if store == nil {
+		return
+	}
+	if err := store.SaveWorkloadAction(action); err != nil {
+		log.Printf("Failed to record %s of %s %s/%s in cluster %q: %v", action.Action, action.Kind, action.Namespace, action.Name, action.Cluster, err)
+	}
+}
+
+// workloadActionOutcome tells actions refused by the checks of this service from those the API server failed
+func workloadActionOutcome(err error) string {
+	for _, refusal := range []error{ErrorNamespaceForbidden, ErrorReasonRequired, ErrorReplicasRequired, ErrorReplicasOutOfRange, ErrorDeploymentPaused, ErrorNoPreviousRevision} {
+		if errors.Is(err, refusal) {
+			return actionRefused
+		}
+	}
+	return actionFailed
+}
+
+// workloadKindFromRequest resolves the {kind} path segment, writing a 404 for kinds without actions
+func workloadKindFromRequest(w http.ResponseWriter, r *http.Request) (workloadKind, bool) {
+	kind, ok := workloadKinds[r.PathValue("kind")]
+	if !ok {
+		utils.WriteJSONError(w, fmt.Sprintf("%v: %s", ErrorUnknownWorkloadKind, r.PathValue("kind")), http.StatusNotFound)
+	}
+	return kind, ok
+}
+
+// runWorkloadAction decodes the action request, checks the caller may change the namespace, runs the action
+// and answers with its audit log entry. Refused and failed attempts are recorded with their error.
+func runWorkloadAction(w http.ResponseWriter, r *http.Request, kind workloadKind, action string,
+	run func(c *Cluster, request models.WorkloadActionRequest) (map[string]interface{}, error)) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	namespace := r.PathValue("ns")
+	entry := &models.WorkloadAction{
+		Cluster:   c.Name,
+		Namespace: namespace,
+		Kind:      kind.Kind,
+		Name:      r.PathValue("name"),
+		Action:    action,
+		Author:    utils.IdentityFromRequest(r),
+		CreatedAt: time.Now(),
+		Outcome:   actionSucceeded,
+	}
+	fail := func(err error) {
+		entry.Outcome = workloadActionOutcome(err)
+		entry.Error = err.Error()
+		recordWorkloadAction(entry)
+	}
+
+	var request models.WorkloadActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fail(fmt.Errorf("invalid JSON payload: %w", err))
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	entry.Reason = request.Reason
+
+	if !utils.CanAccessNamespace(r, namespace) {
+		fail(fmt.Errorf("%w: %s", ErrorNamespaceForbidden, namespace))
+		writeNamespaceForbidden(w, namespace)
+		return
+	}
+	if request.Reason == "" {
+		fail(ErrorReasonRequired)
+		utils.WriteJSONError(w, ErrorReasonRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	details, err := run(c, request)
+	if err != nil {
+		fail(err)
+		writeWorkloadActionError(w, err)
+		return
+	}
+	entry.Details = details
+	recordWorkloadAction(entry)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+func writeWorkloadActionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrorReplicasRequired):
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrorReplicasOutOfRange):
+		utils.WriteJSONError(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrorDeploymentPaused), errors.Is(err, ErrorNoPreviousRevision):
+		utils.WriteJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		writeKubernetesError(w, "Failed to run workload action", err)
+	}
+}
+
+// POST /namespaces/{ns}/{kind}/{name}/restart
+// RestartWorkloadHandler rolls the pods of a deployment or statefulset
+func RestartWorkloadHandler(w http.ResponseWriter, r *http.Request) {
+	kind, ok := workloadKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+	runWorkloadAction(w, r, kind, "restart", func(c *Cluster, _ models.WorkloadActionRequest) (map[string]interface{}, error) {
+		return RestartWorkload(c, kind, r.PathValue("ns"), r.PathValue("name"))
+	})
+}
+
+// POST /namespaces/{ns}/{kind}/{name}/scale
+// ScaleWorkloadHandler sets the replicas of a deployment or statefulset within SCALE_MIN_REPLICAS and SCALE_MAX_REPLICAS
+func ScaleWorkloadHandler(w http.ResponseWriter, r *http.Request) {
+	kind, ok := workloadKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+	runWorkloadAction(w, r, kind, "scale", func(c *Cluster, request models.WorkloadActionRequest) (map[string]interface{}, error) {
+		if request.Replicas == nil {
+			return nil, ErrorReplicasRequired
+		}
+		return ScaleWorkload(c, kind, r.PathValue("ns"), r.PathValue("name"), *request.Replicas)
+	})
+}
+
+// POST /namespaces/{ns}/deployments/{name}/rollback
+// RollbackDeploymentHandler returns a deployment to the pod template of its previous ReplicaSet
+func RollbackDeploymentHandler(w http.ResponseWriter, r *http.Request) {
+	runWorkloadAction(w, r, workloadKinds["deployments"], "rollback", func(c *Cluster, _ models.WorkloadActionRequest) (map[string]interface{}, error) {
+		return RollbackDeployment(c, r.PathValue("ns"), r.PathValue("name"))
+	})
+}
+
+// GET /audit/actions
+// WorkloadActionsGETHandler returns the newest entries of the workload action audit log, filtered by
+// cluster, namespace, kind, name, author and outcome, within the namespaces the caller may access
+func WorkloadActionsGETHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultAuditLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			utils.WriteJSONError(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	filters := map[string]string{}
+	for _, param := range []string{"cluster", "namespace", "kind", "name", "author", "outcome"} {
+		filters[param] = query.Get(param)
+	}
+	// The kind is accepted as in the action paths and stored as the Kubernetes kind
+	if kind, ok := workloadKinds[filters["kind"]]; ok {
+		filters["kind"] = kind.Kind
+	}
+
+	var namespaces []string
+	if namespace := filters["namespace"]; namespace != "" {
+		if !utils.CanAccessNamespace(r, namespace) {
+			writeNamespaceForbidden(w, namespace)
+			return
+		}
+	} else if allowed, all := utils.AllowedNamespaces(r); !all {
+		namespaces = append([]string{}, allowed...)
+	}
+
+	actions, err := store.GetWorkloadActions(filters, namespaces, limit)
+	if err != nil {
+		utils.WriteJSONError(w, "Failed to retrieve workload actions", http.StatusInternalServerError)
+		log.Printf("Failed to retrieve workload actions: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(actions); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"main/packages/models"
+
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var alertmanagerConfigGVR = schema.GroupVersionResource{
+	Group:    "monitoring.coreos.com",
+	Version:  "v1alpha1",
+	Resource: "alertmanagerconfigs",
+}
+
+// amcMatchTypes are the match types of the v1alpha1 AlertmanagerConfig matchers, empty means equality
+var amcMatchTypes = []string{"", "=", "!=", "=~", "!~"}
+
+// alertmanagerConfigSpec holds the parts of an AlertmanagerConfig spec the agent checks
+type alertmanagerConfigSpec struct {
+	Route             *amcRoute         `json:"route"`
+	Receivers         []amcReceiver     `json:"receivers"`
+	InhibitRules      []amcInhibitRule  `json:"inhibitRules"`
+	MuteTimeIntervals []amcTimeInterval `json:"muteTimeIntervals"`
+	TimeIntervals     []amcTimeInterval `json:"timeIntervals"`
+}
+
+type amcRoute struct {
+	Receiver            string       `json:"receiver"`
+	GroupWait           string       `json:"groupWait"`
+	GroupInterval       string       `json:"groupInterval"`
+	RepeatInterval      string       `json:"repeatInterval"`
+	Matchers            []amcMatcher `json:"matchers"`
+	MuteTimeIntervals   []string     `json:"muteTimeIntervals"`
+	ActiveTimeIntervals []string     `json:"activeTimeIntervals"`
+	Routes              []amcRoute   `json:"routes"`
+}
+
+type amcMatcher struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	MatchType string `json:"matchType"`
+	// Regex is the deprecated way of asking for =~
+	Regex bool `json:"regex"`
+}
+
+type amcReceiver struct {
+	Name           string `json:"name"`
+	WebhookConfigs []struct {
+		URL       string      `json:"url"`
+		URLSecret interface{} `json:"urlSecret"`
+	} `json:"webhookConfigs"`
+}
+
+type amcInhibitRule struct {
+	SourceMatch []amcMatcher `json:"sourceMatch"`
+	TargetMatch []amcMatcher `json:"targetMatch"`
+	Equal       []string     `json:"equal"`
+}
+
+type amcTimeInterval struct {
+	Name string `json:"name"`
+}
+
+// ValidateAlertmanagerConfig lints an AlertmanagerConfig object and returns every problem found, or nil if it is valid
+func ValidateAlertmanagerConfig(config map[string]interface{}) []models.AlertmanagerConfigValidationError {
+	var errs []models.AlertmanagerConfigValidationError
+	fail := func(field, message string) {
+		errs = append(errs, models.AlertmanagerConfigValidationError{Field: field, Message: message})
+	}
+
+	metadata, _ := config["metadata"].(map[string]interface{})
+	if name, _ := metadata["name"].(string); name == "" {
+		fail("metadata.name", "name is required")
+	}
+
+	var spec alertmanagerConfigSpec
+	rawSpec, ok := config["spec"]
+	if !ok {
+		fail("spec", "spec is required")
+		return errs
+	}
+	data, err := json.Marshal(rawSpec)
+	if err == nil {
+		err = json.Unmarshal(data, &spec)
+	}
+	if err != nil {
+		fail("spec", fmt.Sprintf("invalid spec: %v", err))
+		return errs
+	}
+
+	receivers := map[string]bool{}
+	for i, receiver := range spec.Receivers {
+		field := fmt.Sprintf("spec.receivers[%d]", i)
+		switch {
+		case receiver.Name == "":
+			fail(field+".name", "receiver name is required")
+		case receivers[receiver.Name]:
+			fail(field+".name", fmt.Sprintf("duplicate receiver name %q", receiver.Name))
+		}
+		receivers[receiver.Name] = true
+
+		for j, webhook := range receiver.WebhookConfigs {
+			webhookField := fmt.Sprintf("%s.webhookConfigs[%d]", field, j)
+			if webhook.URL == "" && webhook.URLSecret == nil {
+				fail(webhookField, "one of url or urlSecret is required")
+			} else if webhook.URL != "" {
+				if u, err := url.Parse(webhook.URL); err != nil || u.Scheme == "" || u.Host == "" {
+					fail(webhookField+".url", fmt.Sprintf("invalid URL %q", webhook.URL))
+				}
+			}
+		}
+	}
+
+	intervals := map[string]bool{}
+	for _, list := range []struct {
+		field     string
+		intervals []amcTimeInterval
+	}{{"spec.muteTimeIntervals", spec.MuteTimeIntervals}, {"spec.timeIntervals", spec.TimeIntervals}} {
+		for i, interval := range list.intervals {
+			if interval.Name == "" {
+				fail(fmt.Sprintf("%s[%d].name", list.field, i), "time interval name is required")
+			}
+			intervals[interval.Name] = true
+		}
+	}
+
+	if spec.Route != nil {
+		// The operator nests the route under the global one, so the top-level route needs its own receiver
+		if spec.Route.Receiver == "" {
+			fail("spec.route.receiver", "receiver is required on the top-level route")
+		}
+		validateAMCRoute(*spec.Route, "spec.route", receivers, intervals, fail)
+	}
+
+	for i, rule := range spec.InhibitRules {
+		field := fmt.Sprintf("spec.inhibitRules[%d]", i)
+		validateAMCMatchers(rule.SourceMatch, field+".sourceMatch", fail)
+		validateAMCMatchers(rule.TargetMatch, field+".targetMatch", fail)
+		for j, name := range rule.Equal {
+			if !model.LabelName(name).IsValid() {
+				fail(fmt.Sprintf("%s.equal[%d]", field, j), fmt.Sprintf("invalid label name %q", name))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateAMCRoute(route amcRoute, field string, receivers, intervals map[string]bool, fail func(field, message string)) {
+	if route.Receiver != "" && !receivers[route.Receiver] {
+		fail(field+".receiver", fmt.Sprintf("receiver %q is not defined", route.Receiver))
+	}
+
+	for _, duration := range [][2]string{{"groupWait", route.GroupWait}, {"groupInterval", route.GroupInterval}, {"repeatInterval", route.RepeatInterval}} {
+		name, value := duration[0], duration[1]
+		if value == "" {
+			continue
+		}
+		if d, err := model.ParseDuration(value); err != nil {
+			fail(field+"."+name, err.Error())
+		} else if d == 0 && name != "groupWait" {
+			fail(field+"."+name, name+" must be greater than 0")
+		}
+	}
+
+	validateAMCMatchers(route.Matchers, field+".matchers", fail)
+
+	for _, refs := range []struct {
+		name  string
+		names []string
+	}{{"muteTimeIntervals", route.MuteTimeIntervals}, {"activeTimeIntervals", route.ActiveTimeIntervals}} {
+		for i, name := range refs.names {
+			if !intervals[name] {
+				fail(fmt.Sprintf("%s.%s[%d]", field, refs.name, i), fmt.Sprintf("time interval %q is not defined", name))
+			}
+		}
+	}
+
+	for i, child := range route.Routes {
+		validateAMCRoute(child, fmt.Sprintf("%s.routes[%d]", field, i), receivers, intervals, fail)
+	}
+}
+
+func validateAMCMatchers(matchers []amcMatcher, field string, fail func(field, message string)) {
+	for i, m := range matchers {
+		matcherField := fmt.Sprintf("%s[%d]", field, i)
+		if !model.LabelName(m.Name).IsValid() {
+			fail(matcherField+".name", fmt.Sprintf("invalid label name %q", m.Name))
+		}
+
+		known := false
+		for _, t := range amcMatchTypes {
+			known = known || m.MatchType == t
+		}
+		if !known {
+			fail(matcherField+".matchType", fmt.Sprintf("unknown match type %q, expected one of =, !=, =~, !~", m.MatchType))
+			continue
+		}
+		if m.MatchType != "" && m.Regex {
+			fail(matcherField+".regex", "regex is deprecated and cannot be combined with matchType")
+		}
+
+		if m.Regex || m.MatchType == "=~" || m.MatchType == "!~" {
+			if _, err := regexp.Compile("^(?:" + m.Value + ")$"); err != nil {
+				fail(matcherField+".value", err.Error())
+			}
+		}
+	}
+}
+
+// ListAlertmanagerConfigs returns the AlertmanagerConfigs of the given namespaces, where an empty namespace means all of them
+func ListAlertmanagerConfigs(c *Cluster, namespaces []string) ([]map[string]interface{}, error) {
+	configs := []map[string]interface{}{}
+	for _, namespace := range namespaces {
+		list, err := c.dynamicClient.Resource(alertmanagerConfigGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			configs = append(configs, item.Object)
+		}
+	}
+
+	sort.SliceStable(configs, func(i, j int) bool {
+		ni, _ := configs[i]["metadata"].(map[string]interface{})
+		nj, _ := configs[j]["metadata"].(map[string]interface{})
+		return fmt.Sprint(ni["namespace"], "/", ni["name"]) < fmt.Sprint(nj["namespace"], "/", nj["name"])
+	})
+	return configs, nil
+}
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"fmt"
+	"main/packages/models"
+	"main/packages/utils"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// maxBacktestPoints mirrors the Prometheus limit of points per series in a range query
+	maxBacktestPoints   = 11000
+	defaultBacktestStep = time.Minute
+)
+
+// Backtest replays an alert rule expression over a historical range and simulates the
+// pending and firing transitions Prometheus would have gone through at every step.
+func Backtest(request models.BacktestRequest) (*models.BacktestResponse, error) {
+	if request.Expr == "" {
+		return nil, fmt.Errorf("%w: expr is required", ErrorInvalidBacktest)
+	}
+	if result := utils.ValidatePromQL(request.Expr); !result.Valid {
+		return nil, fmt.Errorf("%w: invalid expr: %s", ErrorInvalidBacktest, result.Error)
+	}
+
+	start, end := request.Start.UTC().Truncate(time.Second), request.End.UTC().Truncate(time.Second)
+	if start.IsZero() || end.IsZero() || !start.Before(end) {
+		return nil, fmt.Errorf("%w: start must be before end", ErrorInvalidBacktest)
+	}
+
+	step := defaultBacktestStep
+	if request.Step != "" {
+		d, err := model.ParseDuration(request.Step)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: invalid step %q", ErrorInvalidBacktest, request.Step)
+		}
+		step = time.Duration(d)
+	}
+	if end.Sub(start)/step > maxBacktestPoints {
+		return nil, fmt.Errorf("%w: range too long for step %s, at most %d points are allowed", ErrorInvalidBacktest, model.Duration(step), maxBacktestPoints)
+	}
+
+	var hold time.Duration
+	if request.For != "" {
+		d, err := model.ParseDuration(request.For)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid for %q", ErrorInvalidBacktest, request.For)
+		}
+		hold = time.Duration(d)
+	}
+
+	series, err := utils.QueryRangePromQL(request.Expr, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.BacktestResponse{
+		Expr:   request.Expr,
+		For:    request.For,
+		Start:  start,
+		End:    end,
+		Step:   model.Duration(step).String(),
+		Series: make([]models.BacktestSeries, 0, len(series)),
+	}
+	for _, s := range series {
+		result := simulateAlert(s, start, end, step, hold)
+
+		// Alert labels are the series labels without the metric name, plus the alertname
+		result.Labels = make(map[string]string, len(s.Metric)+1)
+		for name, value := range s.Metric {
+			if name != model.MetricNameLabel {
+				result.Labels[name] = value
+			}
+		}
+		if request.Alertname != "" {
+			result.Labels[model.AlertNameLabel] = request.Alertname
+		}
+
+		response.Firings += result.Firings
+		response.Series = append(response.Series, result)
+	}
+	sort.SliceStable(response.Series, func(i, j int) bool {
+		return response.Series[i].Firings > response.Series[j].Firings
+	})
+
+	if request.Compare && request.Alertname != "" {
+		stored, err := store.GetAlertsByNameInRange(request.Alertname, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if stored == nil {
+			stored = []models.AlertResponse{}
+		}
+		response.Comparison = &models.BacktestComparison{
+			Stored: len(stored),
+			Alerts: stored,
+		}
+	}
+
+	return response, nil
+}
+
+// simulateAlert walks the evaluation steps of a single series. A sample present at a step means the
+// alert condition holds; the alert fires once it held for hold and resolves at the first step without a sample.
+func simulateAlert(series utils.PromQLRangeSeries, start, end time.Time, step, hold time.Duration) models.BacktestSeries {
+	present := make(map[int]bool, len(series.Samples))
+	for _, sample := range series.Samples {
+		present[int(math.Round(float64(sample.Time.Sub(start))/float64(step)))] = true
+	}
+
+	result := models.BacktestSeries{Intervals: []models.BacktestInterval{}}
+	var current *models.BacktestInterval
+	steps := int(end.Sub(start) / step)
+	for k := 0; k <= steps; k++ {
+		t := start.Add(time.Duration(k) * step)
+
+		if present[k] {
+			if current == nil {
+				current = &models.BacktestInterval{ActiveAt: t}
+			}
+			if current.FiringAt == nil && t.Sub(current.ActiveAt) >= hold {
+				firingAt := t
+				current.FiringAt = &firingAt
+				result.Firings++
+			}
+			continue
+		}
+
+		if current != nil {
+			if current.FiringAt != nil {
+				resolvedAt := t
+				current.ResolvedAt = &resolvedAt
+			}
+			result.Intervals = append(result.Intervals, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		result.Intervals = append(result.Intervals, *current)
+	}
+
+	return result
+}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"main/packages/models"
+	"main/packages/utils"
+)
+
+func TestSimulateAlert(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Minute)
+	step := time.Minute
+
+	// interval is an expected alert interval in steps from start, firing and resolved are -1 when unset
+	type interval struct {
+		active, firing, resolved int
+	}
+
+	tests := []struct {
+		name string
+		hold time.Duration
+		// steps are the evaluation steps at which the expression returns a sample
+		steps []int
+		// offset shifts every sample off its step, as range query timestamps may be
+		offset    time.Duration
+		want      []interval
+		wantFires int
+	}{
+		{
+			name: "never active",
+			hold: 5 * time.Minute,
+		},
+		{
+			name:  "pending without firing",
+			hold:  5 * time.Minute,
+			steps: []int{2, 3, 4},
+			want:  []interval{{2, -1, -1}},
+		},
+		{
+			name:      "fires after for and resolves",
+			hold:      3 * time.Minute,
+			steps:     []int{1, 2, 3, 4, 5, 6, 7},
+			want:      []interval{{1, 4, 8}},
+			wantFires: 1,
+		},
+		{
+			name:      "fires at once without for",
+			steps:     []int{3},
+			want:      []interval{{3, 3, 4}},
+			wantFires: 1,
+		},
+		{
+			name:      "still firing at the end",
+			hold:      2 * time.Minute,
+			steps:     []int{6, 7, 8, 9, 10},
+			want:      []interval{{6, 8, -1}},
+			wantFires: 1,
+		},
+		{
+			name:      "fires twice",
+			hold:      time.Minute,
+			steps:     []int{0, 1, 2, 5, 6},
+			want:      []interval{{0, 1, 3}, {5, 6, 7}},
+			wantFires: 2,
+		},
+		{
+			name:  "a gap resets pending",
+			hold:  3 * time.Minute,
+			steps: []int{0, 1, 3, 4, 5},
+			want:  []interval{{0, -1, -1}, {3, -1, -1}},
+		},
+		{
+			name:      "samples off their step",
+			hold:      time.Minute,
+			steps:     []int{2, 3},
+			offset:    10 * time.Second,
+			want:      []interval{{2, 3, 4}},
+			wantFires: 1,
+		},
+	}
+
+	at := func(k int) *time.Time {
+		if k < 0 {
+			return nil
+		}
+		t := start.Add(time.Duration(k) * step)
+		return &t
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := utils.PromQLRangeSeries{Metric: map[string]string{"job": "api"}}
+			for _, k := range tt.steps {
+				series.Samples = append(series.Samples, utils.PromQLRangeSample{Time: at(k).Add(tt.offset), Value: 1})
+			}
+
+			want := []models.BacktestInterval{}
+			for _, i := range tt.want {
+				want = append(want, models.BacktestInterval{ActiveAt: *at(i.active), FiringAt: at(i.firing), ResolvedAt: at(i.resolved)})
+			}
+
+			got := simulateAlert(series, start, end, step, tt.hold)
+			if !reflect.DeepEqual(got.Intervals, want) {
+				t.Errorf("intervals = %+v, want %+v", got.Intervals, want)
+			}
+			if got.Firings != tt.wantFires {
+				t.Errorf("firings = %d, want %d", got.Firings, tt.wantFires)
+			}
+		})
+	}
+}
+
+func TestBacktestValidation(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		request models.BacktestRequest
+	}{
+		{name: "missing expr", request: models.BacktestRequest{Start: start, End: start.Add(time.Hour)}},
+		{name: "invalid expr", request: models.BacktestRequest{Expr: "rate(up[5m]", Start: start, End: start.Add(time.Hour)}},
+		{name: "end before start", request: models.BacktestRequest{Expr: "up == 0", Start: start, End: start.Add(-time.Hour)}},
+		{name: "invalid step", request: models.BacktestRequest{Expr: "up == 0", Start: start, End: start.Add(time.Hour), Step: "0s"}},
+		{name: "too many points", request: models.BacktestRequest{Expr: "up == 0", Start: start, End: start.Add(30 * 24 * time.Hour), Step: "1m"}},
+		{name: "invalid for", request: models.BacktestRequest{Expr: "up == 0", Start: start, End: start.Add(time.Hour), For: "a while"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Backtest(tt.request); !errors.Is(err, ErrorInvalidBacktest) {
+				t.Errorf("Backtest() error = %v, want ErrorInvalidBacktest", err)
+			}
+		})
+	}
+}
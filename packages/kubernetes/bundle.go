@@ -0,0 +1,195 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"main/packages/models"
+	"main/packages/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// ExportRules returns the PrometheusRules of a namespace, or of all namespaces when it is empty,
+// stripped of server-side fields and sorted by namespace and name
+func ExportRules(c *Cluster, namespace string) ([]map[string]interface{}, error) {
+	list, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		if list.Items[i].GetNamespace() != list.Items[j].GetNamespace() {
+			return list.Items[i].GetNamespace() < list.Items[j].GetNamespace()
+		}
+		return list.Items[i].GetName() < list.Items[j].GetName()
+	})
+
+	rules := make([]map[string]interface{}, 0, len(list.Items))
+	for _, item := range list.Items {
+		rule := stripServerFields(item.Object)
+		// List items may come without type information
+		rule["apiVersion"] = prometheusRuleGVR.GroupVersion().String()
+		rule["kind"] = "PrometheusRule"
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// EncodeRulesYAML writes the rules as a multi-document YAML stream
+func EncodeRulesYAML(rules []map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, rule := range rules {
+		data, err := yaml.Marshal(rule)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeRulesTar writes one multi-document YAML file per namespace into a tar archive
+func EncodeRulesTar(rules []map[string]interface{}) ([]byte, error) {
+	var namespaces []string
+	byNamespace := map[string][]map[string]interface{}{}
+	for _, rule := range rules {
+		namespace, _, _ := unstructured.NestedString(rule, "metadata", "namespace")
+		if _, ok := byNamespace[namespace]; !ok {
+			namespaces = append(namespaces, namespace)
+		}
+		byNamespace[namespace] = append(byNamespace[namespace], rule)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	now := time.Now()
+	for _, namespace := range namespaces {
+		data, err := EncodeRulesYAML(byNamespace[namespace])
+		if err != nil {
+			return nil, err
+		}
+		header := &tar.Header{
+			Name:    namespace + ".yaml",
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ImportRules applies a YAML or JSON bundle of PrometheusRules. Every document is linted and compared
+// with the live object; with dryRun the changes go through a server-side dry run and nothing is written.
+// Documents without a namespace are placed in defaultNamespace.
+func ImportRules(c *Cluster, bundle []byte, defaultNamespace string, dryRun bool, author string) (models.RuleImportResult, error) {
+	result := models.RuleImportResult{
+		DryRun:    dryRun,
+		Created:   []string{},
+		Updated:   []string{},
+		Unchanged: []string{},
+		Failed:    []models.RuleImportFailure{},
+	}
+
+	var options []string
+	if dryRun {
+		options = []string{metav1.DryRunAll}
+	}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(bundle), 4096)
+	for document := 0; ; document++ {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return result, fmt.Errorf("document %d: %v", document, err)
+		}
+		if object == nil {
+			// Empty documents, e.g. a trailing "---"
+			continue
+		}
+
+		rule := &unstructured.Unstructured{Object: object}
+		if rule.GetNamespace() == "" {
+			rule.SetNamespace(defaultNamespace)
+		}
+		key := rule.GetNamespace() + "/" + rule.GetName()
+		fail := func(err error, errs []models.RuleValidationError) {
+			result.Failed = append(result.Failed, models.RuleImportFailure{Document: document, Rule: key, Error: err.Error(), Errors: errs})
+		}
+
+		if rule.GetKind() != "PrometheusRule" {
+			fail(fmt.Errorf("unsupported kind %q", rule.GetKind()), nil)
+			continue
+		}
+		if rule.GetNamespace() == "" {
+			fail(fmt.Errorf("namespace is required"), nil)
+			continue
+		}
+		if errs := ValidatePrometheusRule(rule.Object); len(errs) > 0 {
+			fail(fmt.Errorf("PrometheusRule validation failed"), errs)
+			continue
+		}
+
+		desired := &unstructured.Unstructured{Object: stripServerFields(rule.Object)}
+		resource := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(desired.GetNamespace())
+		existing, err := getRuleIfExists(c, desired.GetNamespace(), desired.GetName())
+		if err != nil {
+			fail(err, nil)
+			continue
+		}
+
+		if existing == nil {
+			created, err := resource.Create(context.TODO(), desired, metav1.CreateOptions{DryRun: options})
+			if err != nil {
+				fail(err, nil)
+				continue
+			}
+			if !dryRun {
+				recordRevision(c, desired.GetNamespace(), desired.GetName(), "create", author, nil, created)
+			}
+			result.Created = append(result.Created, key)
+			continue
+		}
+
+		if len(utils.DiffObjects(stripServerFields(existing.Object), desired.Object)) == 0 {
+			result.Unchanged = append(result.Unchanged, key)
+			continue
+		}
+
+		desired.SetResourceVersion(existing.GetResourceVersion())
+		updated, err := resource.Update(context.TODO(), desired, metav1.UpdateOptions{DryRun: options})
+		if err != nil {
+			fail(err, nil)
+			continue
+		}
+		if !dryRun {
+			recordRevision(c, desired.GetNamespace(), desired.GetName(), "update", author, existing, updated)
+		}
+		result.Updated = append(result.Updated, key)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,190 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+
+	"main/packages/models"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Kinds served from the informer cache
+const (
+	cachePods        = "pods"
+	cacheNodes       = "nodes"
+	cacheNamespaces  = "namespaces"
+	cacheDeployments = "deployments"
+)
+
+// clusterCache holds the shared informers of a cluster. Listings are answered from the listers
+// once the informer of their kind has synced.
+type clusterCache struct {
+	factory     informers.SharedInformerFactory
+	pods        corelisters.PodLister
+	nodes       corelisters.NodeLister
+	namespaces  corelisters.NamespaceLister
+	deployments appslisters.DeploymentLister
+	synced      map[string]cache.InformerSynced
+}
+
+// StartInformerCaches starts shared informers for pods, nodes, namespaces and deployments in every registered
+// cluster. It does not wait for them to sync; until then listings read live and GET /readyz reports not ready.
+func StartInformerCaches() {
+	for _, name := range ClusterNames() {
+		c := clusters[name]
+		// managedFields make up a large part of every object and are never needed by the agent
+		factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, 0, informers.WithTransform(stripManagedFields))
+
+		cc := &clusterCache{factory: factory, synced: map[string]cache.InformerSynced{}}
+		pods := factory.Core().V1().Pods()
+		nodes := factory.Core().V1().Nodes()
+		namespaces := factory.Core().V1().Namespaces()
+		deployments := factory.Apps().V1().Deployments()
+		cc.pods, cc.synced[cachePods] = pods.Lister(), pods.Informer().HasSynced
+		cc.nodes, cc.synced[cacheNodes] = nodes.Lister(), nodes.Informer().HasSynced
+		cc.namespaces, cc.synced[cacheNamespaces] = namespaces.Lister(), namespaces.Informer().HasSynced
+		cc.deployments, cc.synced[cacheDeployments] = deployments.Lister(), deployments.Informer().HasSynced
+
+		factory.Start(make(chan struct{}))
+		c.cache = cc
+		log.Printf("Started informer cache for cluster %q", name)
+	}
+}
+
+func stripManagedFields(object interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(object); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return object, nil
+}
+
+// fromCache reports whether a listing of the kind can be answered from the informer cache.
+// live=true on the request always reads from the API server, and so do field selectors and pagination,
+// which only the API server implements.
+func (c *Cluster) fromCache(r *http.Request, kind string, opts metav1.ListOptions) bool {
+	if c.cache == nil || r.URL.Query().Get("live") == "true" {
+		return false
+	}
+	if opts.FieldSelector != "" || opts.Limit != 0 || opts.Continue != "" {
+		return false
+	}
+	return c.cache.synced[kind]()
+}
+
+// sortCached orders lister results by namespace and name like the API server does, and dereferences them
+func sortCached[T any, P interface {
+	*T
+	metav1.Object
+}](items []P) []T {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].GetNamespace() != items[j].GetNamespace() {
+			return items[i].GetNamespace() < items[j].GetNamespace()
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+	out := make([]T, len(items))
+	for i, item := range items {
+		out[i] = *item
+	}
+	return out
+}
+
+// listPods reads pods from the informer cache when possible and from the API server otherwise.
+// The list metadata is nil for cached reads, which are never paginated.
+func listPods(c *Cluster, r *http.Request, namespace string, opts metav1.ListOptions) ([]corev1.Pod, metav1.ListInterface, error) {
+	if c.fromCache(r, cachePods, opts) {
+		selector, _ := labels.Parse(opts.LabelSelector)
+		cached, err := c.cache.pods.Pods(namespace).List(selector)
+		return sortCached(cached), nil, err
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pods.Items, &pods.ListMeta, nil
+}
+
+func listNodes(c *Cluster, r *http.Request, opts metav1.ListOptions) ([]corev1.Node, metav1.ListInterface, error) {
+	if c.fromCache(r, cacheNodes, opts) {
+		selector, _ := labels.Parse(opts.LabelSelector)
+		cached, err := c.cache.nodes.List(selector)
+		return sortCached(cached), nil, err
+	}
+
+	nodes, err := c.clientset.CoreV1().Nodes().List(context.TODO(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nodes.Items, &nodes.ListMeta, nil
+}
+
+func listNamespaces(c *Cluster, r *http.Request, opts metav1.ListOptions) ([]corev1.Namespace, metav1.ListInterface, error) {
+	if c.fromCache(r, cacheNamespaces, opts) {
+		selector, _ := labels.Parse(opts.LabelSelector)
+		cached, err := c.cache.namespaces.List(selector)
+		return sortCached(cached), nil, err
+	}
+
+	namespaces, err := c.clientset.CoreV1().Namespaces().List(context.TODO(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return namespaces.Items, &namespaces.ListMeta, nil
+}
+
+func listDeployments(c *Cluster, r *http.Request, namespace string, opts metav1.ListOptions) ([]appsv1.Deployment, metav1.ListInterface, error) {
+	if c.fromCache(r, cacheDeployments, opts) {
+		selector, _ := labels.Parse(opts.LabelSelector)
+		cached, err := c.cache.deployments.Deployments(namespace).List(selector)
+		return sortCached(cached), nil, err
+	}
+
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return deployments.Items, &deployments.ListMeta, nil
+}
+
+// GET /readyz
+// ReadinessGETHandler reports the sync state of the informer caches of every cluster. It answers 503 until the
+// default cluster has synced; other clusters are reported but do not affect readiness, so one unreachable
+// remote cluster does not take the agent out of service.
+func ReadinessGETHandler(w http.ResponseWriter, r *http.Request) {
+	response := models.ReadinessResponse{Ready: true, Clusters: []models.ClusterCacheStatus{}}
+	for _, name := range ClusterNames() {
+		c := clusters[name]
+		status := models.ClusterCacheStatus{Cluster: name, Cached: c.cache != nil, Synced: true, Kinds: map[string]bool{}}
+		if c.cache != nil {
+			for kind, synced := range c.cache.synced {
+				status.Kinds[kind] = synced()
+				status.Synced = status.Synced && status.Kinds[kind]
+			}
+		}
+		if name == defaultCluster {
+			response.Ready = status.Synced
+		}
+		response.Clusters = append(response.Clusters, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !response.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
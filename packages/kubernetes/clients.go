@@ -0,0 +1,399 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"main/packages/config"
+	"main/packages/models"
+	"main/packages/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// allClusters is the cluster parameter value asking a listing to aggregate every registered cluster
+const allClusters = "*"
+
+// Cluster holds the clients of one Kubernetes cluster known to the agent
+type Cluster struct {
+	Name   string
+	Host   string
+	Source string
+
+	clientset     *kubernetes.Clientset
+	dynamicClient dynamic.Interface
+	// metadataClient reads kinds whose content must not leave the API server, like the data of ConfigMaps
+	metadataClient metadata.Interface
+	// mapper resolves the versions of the generic resource kinds through discovery
+	mapper *restmapper.DeferredDiscoveryRESTMapper
+	// cache is nil unless informer caching is enabled
+	cache *clusterCache
+}
+
+// The registry is filled once at startup and only read afterwards
+var (
+	clusters       = map[string]*Cluster{}
+	defaultCluster string
+)
+
+// InitKubernetesClients registers the clusters the agent works with. The default cluster uses the in-cluster
+// service account, falling back to KUBECONFIG or ~/.kube/config; a non-empty kubeContext skips the in-cluster
+// configuration and selects that kubeconfig context. Further clusters come from the kubeconfig contexts listed
+// in KUBE_CONTEXTS and the kubeconfig files in CLUSTERS_DIR, e.g. mounted secrets.
+// An error is returned only when no cluster at all could be registered.
+func InitKubernetesClients(kubeContext string) error {
+	primaryErr := registerDefaultCluster(kubeContext)
+	if primaryErr != nil {
+		log.Printf("Default cluster unavailable: %v", primaryErr)
+	}
+
+	if contexts := config.GetEnv("KUBE_CONTEXTS", ""); contexts != "" {
+		registerKubeconfigContexts(contexts)
+	}
+	if dir := config.GetEnv("CLUSTERS_DIR", ""); dir != "" {
+		registerClusterFiles(dir)
+	}
+
+	if len(clusters) == 0 {
+		return primaryErr
+	}
+	if defaultCluster == "" {
+		defaultCluster = ClusterNames()[0]
+	}
+	return nil
+}
+
+func registerDefaultCluster(kubeContext string) error {
+	config, source, name, err := loadRESTConfig(kubeContext)
+	if err != nil {
+		return err
+	}
+	if override := os.Getenv("CLUSTER_NAME"); override != "" {
+		name = override
+	}
+
+	if err := registerCluster(name, source, config); err != nil {
+		return err
+	}
+	defaultCluster = name
+	return nil
+}
+
+// loadRESTConfig returns the client configuration, a description of where it came from and a cluster name
+func loadRESTConfig(kubeContext string) (*rest.Config, string, string, error) {
+	var inClusterErr error
+	if kubeContext == "" {
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			return config, "the in-cluster service account", "in-cluster", nil
+		}
+		inClusterErr = err
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	)
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		if inClusterErr != nil {
+			return nil, "", "", fmt.Errorf("not running in a cluster (%v) and no usable kubeconfig: %v", inClusterErr, err)
+		}
+		return nil, "", "", fmt.Errorf("failed to load kubeconfig context %q: %v", kubeContext, err)
+	}
+
+	if kubeContext == "" {
+		if raw, err := clientConfig.RawConfig(); err == nil {
+			kubeContext = raw.CurrentContext
+		}
+	}
+	return config, fmt.Sprintf("kubeconfig context %q", kubeContext), kubeContext, nil
+}
+
+// registerKubeconfigContexts registers a comma-separated list of kubeconfig contexts, or all of them for "*".
+// Clusters are named after their context.
+func registerKubeconfigContexts(contexts string) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	raw, err := loadingRules.Load()
+	if err != nil {
+		log.Printf("Failed to load kubeconfig for KUBE_CONTEXTS: %v", err)
+		return
+	}
+
+	var names []string
+	if contexts == allClusters {
+		for name := range raw.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	} else {
+		for _, name := range strings.Split(contexts, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if _, exists := clusters[name]; exists {
+			continue
+		}
+		config, err := clientcmd.NewNonInteractiveClientConfig(*raw, name, &clientcmd.ConfigOverrides{}, loadingRules).ClientConfig()
+		if err != nil {
+			log.Printf("Skipping kubeconfig context %q: %v", name, err)
+			continue
+		}
+		if err := registerCluster(name, fmt.Sprintf("kubeconfig context %q", name), config); err != nil {
+			log.Printf("Skipping kubeconfig context %q: %v", name, err)
+		}
+	}
+}
+
+// registerClusterFiles registers every kubeconfig file of a directory, named after the file without extension.
+// Hidden entries are skipped, which also skips the ..data links of mounted secrets.
+func registerClusterFiles(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Failed to read CLUSTERS_DIR %s: %v", dir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if _, exists := clusters[name]; exists {
+			log.Printf("Skipping %s: cluster %q is already registered", path, name)
+			continue
+		}
+
+		config, err := clientcmd.BuildConfigFromFlags("", path)
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			continue
+		}
+		if err := registerCluster(name, "kubeconfig file "+path, config); err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+		}
+	}
+}
+
+func registerCluster(name, source string, config *rest.Config) error {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	clusters[name] = &Cluster{
+		Name:           name,
+		Host:           config.Host,
+		Source:         source,
+		clientset:      clientset,
+		dynamicClient:  dynamicClient,
+		metadataClient: metadataClient,
+		mapper:         restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery())),
+	}
+	log.Printf("Registered cluster %q at %s from %s", name, config.Host, source)
+	return nil
+}
+
+// ClusterNames returns the names of the registered clusters, sorted
+func ClusterNames() []string {
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultCluster returns the cluster used when a request names none, or nil without Kubernetes
+func DefaultCluster() *Cluster {
+	return clusters[defaultCluster]
+}
+
+// KubernetesAvailable reports whether at least one cluster is registered
+func KubernetesAvailable() bool {
+	return len(clusters) > 0
+}
+
+// RequireClients answers 503 while the agent runs without Kubernetes
+func RequireClients(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !KubernetesAvailable() {
+			utils.WriteJSONError(w, ErrorKubernetesUnavailable.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestCluster resolves the cluster query parameter, the default cluster when it is empty.
+// It writes a 404 for unknown clusters and a 400 for cluster=*, which only listings support.
+func requestCluster(w http.ResponseWriter, r *http.Request) (*Cluster, bool) {
+	name := r.URL.Query().Get("cluster")
+	if name == allClusters {
+		utils.WriteJSONError(w, "cluster=* is only supported by listings", http.StatusBadRequest)
+		return nil, false
+	}
+	if name == "" {
+		name = defaultCluster
+	}
+
+	cluster, ok := clusters[name]
+	if !ok {
+		utils.WriteJSONError(w, fmt.Sprintf("%v: %s", ErrorClusterNotFound, name), http.StatusNotFound)
+		return nil, false
+	}
+	return cluster, true
+}
+
+// listItems converts a typed item slice for listClusters
+func listItems[T any](items []T) []interface{} {
+	out := make([]interface{}, len(items))
+	for i := range items {
+		out[i] = items[i]
+	}
+	return out
+}
+
+// listClusters answers a listing from the requested cluster as a plain array, with the continue token of a
+// paginated listing in the X-Continue header. With cluster=* it queries every registered cluster concurrently
+// and answers with the items tagged with their cluster, plus the clusters that failed; it cannot be paginated.
+// The list function may return nil list metadata when the listing is never paginated.
+func listClusters(w http.ResponseWriter, r *http.Request, message string, list func(c *Cluster) ([]interface{}, metav1.ListInterface, error)) {
+	query := r.URL.Query()
+	if query.Get("cluster") != allClusters {
+		c, ok := requestCluster(w, r)
+		if !ok {
+			return
+		}
+		items, meta, err := list(c)
+		if err != nil {
+			writeKubernetesError(w, message, err)
+			return
+		}
+
+		if meta != nil && meta.GetContinue() != "" {
+			w.Header().Set("X-Continue", meta.GetContinue())
+			if remaining := meta.GetRemainingItemCount(); remaining != nil {
+				w.Header().Set("X-Remaining-Item-Count", strconv.FormatInt(*remaining, 10))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(items); err != nil {
+			log.Printf("JSON encoding error: %v", err)
+		}
+		return
+	}
+
+	if query.Get("limit") != "" || query.Get("continue") != "" {
+		utils.WriteJSONError(w, "limit and continue cannot be combined with cluster=*", http.StatusBadRequest)
+		return
+	}
+
+	names := ClusterNames()
+	results := make([][]interface{}, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, c *Cluster) {
+			defer wg.Done()
+			results[i], _, errs[i] = list(c)
+		}(i, clusters[name])
+	}
+	wg.Wait()
+
+	response := models.ClusterListResponse{
+		Items:  []map[string]interface{}{},
+		Errors: []models.ClusterError{},
+	}
+	for i, name := range names {
+		if errs[i] != nil {
+			log.Printf("%s in cluster %q: %v", message, name, errs[i])
+			response.Errors = append(response.Errors, models.ClusterError{Cluster: name, Error: errs[i].Error()})
+			continue
+		}
+		for _, item := range results[i] {
+			tagged, err := tagCluster(item, name)
+			if err != nil {
+				utils.WriteJSONError(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+				return
+			}
+			response.Items = append(response.Items, tagged)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// tagCluster turns an item into a JSON object with a top-level cluster field
+func tagCluster(item interface{}, cluster string) (map[string]interface{}, error) {
+	object, ok := item.(map[string]interface{})
+	if !ok {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, err
+		}
+	} else {
+		copied := make(map[string]interface{}, len(object)+1)
+		for k, v := range object {
+			copied[k] = v
+		}
+		object = copied
+	}
+	object["cluster"] = cluster
+	return object, nil
+}
+
+// GET /clusters
+// ClustersGETHandler returns the registered clusters
+func ClustersGETHandler(w http.ResponseWriter, r *http.Request) {
+	response := []models.ClusterInfo{}
+	for _, name := range ClusterNames() {
+		c := clusters[name]
+		response = append(response, models.ClusterInfo{
+			Name:    c.Name,
+			Host:    c.Host,
+			Source:  c.Source,
+			Default: c.Name == defaultCluster,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package kubernetes
+
+import "fmt"
+
+var (
+	ErrorKubernetesUnavailable = fmt.Errorf("no Kubernetes cluster is available")
+	ErrorClusterNotFound       = fmt.Errorf("unknown cluster")
+
+	ErrorRuleGroupNotFound = fmt.Errorf("rule group not found")
+	ErrorAlertNotFound     = fmt.Errorf("alerting rule not found")
+	ErrorAlertExists       = fmt.Errorf("alerting rule already exists")
+
+	ErrorRevisionNotFound = fmt.Errorf("revision not found")
+	ErrorRevisionDeleted  = fmt.Errorf("revision has no object to restore, the rule was deleted")
+
+	ErrorRuleTestTooLong  = fmt.Errorf("rule test too long")
+	ErrorRuleTestTooLarge = fmt.Errorf("rule test too large")
+	ErrorInvalidBacktest  = fmt.Errorf("invalid backtest request")
+
+	ErrorTemplateNotFound    = fmt.Errorf("rule template not found")
+	ErrorInvalidTemplate     = fmt.Errorf("invalid rule template")
+	ErrorInvalidRenderParams = fmt.Errorf("invalid template parameters")
+
+	ErrorSLONotFound   = fmt.Errorf("SLO not found")
+	ErrorInvalidSLO    = fmt.Errorf("invalid SLO")
+	ErrorNotSLOManaged = fmt.Errorf("PrometheusRule exists but is not managed by an SLO")
+
+	ErrorUnknownMonitorKind = fmt.Errorf("unknown monitor kind, expected servicemonitors or podmonitors")
+
+	ErrorNamespaceForbidden = fmt.Errorf("access to namespace denied")
+
+	ErrorUnknownResource = fmt.Errorf("unknown resource, see GET /resources for the available ones")
+
+	ErrorFingerprintNotFound = fmt.Errorf("no alert with this fingerprint was received")
+	ErrorAlertWithoutPod     = fmt.Errorf("alert labels name no namespace and pod")
+
+	ErrorUnknownWorkloadKind = fmt.Errorf("unknown workload kind, expected deployments or statefulsets")
+	ErrorReasonRequired      = fmt.Errorf("a reason is required for the audit log")
+	ErrorReplicasRequired    = fmt.Errorf("replicas is required")
+	ErrorReplicasOutOfRange  = fmt.Errorf("replicas outside the allowed range")
+	ErrorDeploymentPaused    = fmt.Errorf("deployment is paused, resume it first")
+	ErrorNoPreviousRevision  = fmt.Errorf("no previous revision to roll back to")
+)
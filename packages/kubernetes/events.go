@@ -0,0 +1,342 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/packages/models"
+	"main/packages/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// defaultAlertLookback is how long before an alert started its events and logs are read, the cause of an alert
+// usually precedes it by the for duration of the rule
+const defaultAlertLookback = 15 * time.Minute
+
+// eventFilters maps the filter query parameters of GET /events to event field selectors
+var eventFilters = []struct {
+	Param string
+	Field string
+}{
+	{"kind", "involvedObject.kind"},
+	{"name", "involvedObject.name"},
+	{"reason", "reason"},
+	{"type", "type"},
+}
+
+// alertObjectLabels are the labels of namespaced objects an alert can refer to, as set by kube-state-metrics
+var alertObjectLabels = []struct {
+	Label string
+	Kind  string
+}{
+	{"pod", "Pod"},
+	{"deployment", "Deployment"},
+	{"statefulset", "StatefulSet"},
+	{"daemonset", "DaemonSet"},
+}
+
+// eventFieldSelector adds the filter parameters to the fieldSelector of the request, writing a 400 for an unknown type
+func eventFieldSelector(w http.ResponseWriter, r *http.Request, selector string) (string, bool) {
+	terms := []string{}
+	if selector != "" {
+		terms = append(terms, selector)
+	}
+	for _, filter := range eventFilters {
+		value := r.URL.Query().Get(filter.Param)
+		if value == "" {
+			continue
+		}
+		if filter.Param == "type" && value != corev1.EventTypeNormal && value != corev1.EventTypeWarning {
+			utils.WriteJSONError(w, fmt.Sprintf("Unknown event type %q, expected Normal or Warning", value), http.StatusBadRequest)
+			return "", false
+		}
+		terms = append(terms, fields.OneTermEqualSelector(filter.Field, value).String())
+	}
+	return strings.Join(terms, ","), true
+}
+
+// GET /events
+// EventsGETHandler lists events, filtered by the kind and name of the involved object, reason and type.
+// Events are ordered by the time they were last seen, within a page when the listing is paginated.
+func EventsGETHandler(w http.ResponseWriter, r *http.Request) {
+	opts, ok := listOptionsFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if opts.FieldSelector, ok = eventFieldSelector(w, r, opts.FieldSelector); !ok {
+		return
+	}
+	view, ok := viewFromRequest(w, r)
+	if !ok {
+		return
+	}
+	namespace := namespaceParam(r)
+
+	listClusters(w, r, "Failed to get events", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		events, err := c.clientset.CoreV1().Events(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.SliceStable(events.Items, func(i, j int) bool {
+			_, a := eventTimes(&events.Items[i])
+			_, b := eventTimes(&events.Items[j])
+			return a.Before(b)
+		})
+		if view == viewSummary {
+			return listItems(SummarizeEvents(events.Items)), &events.ListMeta, nil
+		}
+		return listItems(events.Items), &events.ListMeta, nil
+	})
+}
+
+// eventTimes returns when an event was first and last seen. Events recorded through events.k8s.io/v1
+// only carry the event time and their series.
+func eventTimes(event *corev1.Event) (first, last time.Time) {
+	switch {
+	case !event.FirstTimestamp.IsZero():
+		first = event.FirstTimestamp.Time
+	case !event.EventTime.IsZero():
+		first = event.EventTime.Time
+	default:
+		first = event.CreationTimestamp.Time
+	}
+
+	switch {
+	case !event.LastTimestamp.IsZero():
+		last = event.LastTimestamp.Time
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		last = event.Series.LastObservedTime.Time
+	default:
+		last = first
+	}
+	return first, last
+}
+
+// SummarizeEvents projects events to the involved object, reason, message, count and when they were seen
+func SummarizeEvents(events []corev1.Event) []models.EventSummary {
+	summaries := make([]models.EventSummary, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		first, last := eventTimes(event)
+
+		count := event.Count
+		if count == 0 && event.Series != nil {
+			count = event.Series.Count
+		}
+		if count == 0 {
+			count = 1
+		}
+		source := event.Source.Component
+		if source == "" {
+			source = event.ReportingController
+		}
+
+		summaries = append(summaries, models.EventSummary{
+			Namespace: event.InvolvedObject.Namespace,
+			Kind:      event.InvolvedObject.Kind,
+			Name:      event.InvolvedObject.Name,
+			Type:      event.Type,
+			Reason:    event.Reason,
+			Message:   event.Message,
+			Count:     count,
+			Source:    source,
+			FirstSeen: first,
+			LastSeen:  last,
+		})
+	}
+	return summaries
+}
+
+// GET /alerts/{fingerprint}/events
+// AlertEventsGETHandler returns the events of the pod, workload and node named in the labels of a stored alert,
+// seen between the lookback duration (15m by default) before the alert started and its end, or now while it fires.
+// The cluster is taken from the cluster parameter, or else from a cluster label naming a registered cluster.
+func AlertEventsGETHandler(w http.ResponseWriter, r *http.Request) {
+	lookback, ok := lookbackFromRequest(w, r)
+	if !ok {
+		return
+	}
+	alert, ok := storedAlert(w, r)
+	if !ok {
+		return
+	}
+	c, ok := alertCluster(w, r, alert.Labels)
+	if !ok {
+		return
+	}
+
+	response := models.AlertEventsResponse{
+		Fingerprint: alert.Fingerprint,
+		AlertName:   alert.Name,
+		Cluster:     c.Name,
+		From:        alert.StartsAt.Add(-lookback),
+		To:          time.Now().UTC(),
+		Objects:     alertObjects(alert.Labels),
+		Events:      []models.EventSummary{},
+	}
+	if alert.Status == "resolved" && alert.EndsAt.After(alert.StartsAt) {
+		response.To = alert.EndsAt
+	}
+
+	events, err := eventsOfObjects(c, response.Objects, response.From, response.To)
+	if err != nil {
+		writeKubernetesError(w, "Failed to get events", err)
+		return
+	}
+	response.Events = append(response.Events, SummarizeEvents(events)...)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// lookbackFromRequest returns the lookback parameter, 15m when it is empty, writing a 400 for invalid durations
+func lookbackFromRequest(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	value := r.URL.Query().Get("lookback")
+	if value == "" {
+		return defaultAlertLookback, true
+	}
+	lookback, err := time.ParseDuration(value)
+	if err != nil || lookback < 0 {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid lookback %q, expected a duration like 30m", value), http.StatusBadRequest)
+		return 0, false
+	}
+	return lookback, true
+}
+
+// storedAlert looks up the alert of the {fingerprint} path segment in Doris, writing a 404 when it was never received
+func storedAlert(w http.ResponseWriter, r *http.Request) (*models.AlertResponse, bool) {
+	alert, err := store.GetAlertByFingerprint(r.PathValue("fingerprint"))
+	if err != nil {
+		utils.WriteJSONError(w, "Failed to retrieve alert", http.StatusInternalServerError)
+		log.Printf("Failed to retrieve alert %s: %v", r.PathValue("fingerprint"), err)
+		return nil, false
+	}
+	if alert == nil {
+		utils.WriteJSONError(w, fmt.Sprintf("%v: %s", ErrorFingerprintNotFound, r.PathValue("fingerprint")), http.StatusNotFound)
+		return nil, false
+	}
+	return alert, true
+}
+
+// alertCluster resolves the cluster of an alert, preferring the cluster parameter over the cluster label
+func alertCluster(w http.ResponseWriter, r *http.Request, labels map[string]string) (*Cluster, bool) {
+	if r.URL.Query().Get("cluster") == "" {
+		if c, ok := clusters[labels["cluster"]]; ok {
+			return c, true
+		}
+	}
+	return requestCluster(w, r)
+}
+
+// alertObjects returns the objects named in the labels of an alert. Workloads are matched together with
+// the ReplicaSets and pods they own.
+func alertObjects(labels map[string]string) []models.InvolvedObject {
+	objects := []models.InvolvedObject{}
+	if namespace := labels["namespace"]; namespace != "" {
+		for _, label := range alertObjectLabels {
+			if name := labels[label.Label]; name != "" {
+				objects = append(objects, models.InvolvedObject{Kind: label.Kind, Namespace: namespace, Name: name})
+			}
+		}
+	}
+	if node := labels["node"]; node != "" {
+		objects = append(objects, models.InvolvedObject{Kind: "Node", Name: node})
+	}
+	return objects
+}
+
+// eventsOfObjects returns the events of the objects seen within the time window, ordered by the time they were last seen.
+// Namespaced objects are matched against the events of their namespace, node events are selected by name.
+func eventsOfObjects(c *Cluster, objects []models.InvolvedObject, from, to time.Time) ([]corev1.Event, error) {
+	candidates := map[string][]corev1.Event{}
+	for _, object := range objects {
+		if object.Kind == "Node" {
+			selector := fields.AndSelectors(
+				fields.OneTermEqualSelector("involvedObject.kind", "Node"),
+				fields.OneTermEqualSelector("involvedObject.name", object.Name),
+			)
+			events, err := c.clientset.CoreV1().Events(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{FieldSelector: selector.String()})
+			if err != nil {
+				return nil, err
+			}
+			candidates["node/"+object.Name] = events.Items
+			continue
+		}
+		if _, listed := candidates[object.Namespace]; listed {
+			continue
+		}
+		events, err := c.clientset.CoreV1().Events(object.Namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		candidates[object.Namespace] = events.Items
+	}
+
+	matched := []corev1.Event{}
+	seen := map[string]bool{}
+	for _, events := range candidates {
+		for _, event := range events {
+			if seen[string(event.UID)] {
+				continue
+			}
+			first, last := eventTimes(&event)
+			if first.After(to) || last.Before(from) {
+				continue
+			}
+			for _, object := range objects {
+				if refersTo(object, event.InvolvedObject) {
+					seen[string(event.UID)] = true
+					matched = append(matched, event)
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		_, a := eventTimes(&matched[i])
+		_, b := eventTimes(&matched[j])
+		return a.Before(b)
+	})
+	return matched, nil
+}
+
+// refersTo reports whether an event about the referenced object belongs to the alert object. Besides the object
+// itself this matches the objects a workload owns by their generated names: <deployment>-<hash> ReplicaSets,
+// <deployment>-<hash>-<suffix> and <daemonset>-<suffix> pods, and <statefulset>-<ordinal> pods.
+func refersTo(object models.InvolvedObject, ref corev1.ObjectReference) bool {
+	if object.Kind != "Node" && ref.Namespace != object.Namespace {
+		return false
+	}
+	if ref.Kind == object.Kind && ref.Name == object.Name {
+		return true
+	}
+
+	suffix, found := strings.CutPrefix(ref.Name, object.Name+"-")
+	if !found || suffix == "" {
+		return false
+	}
+	switch object.Kind {
+	case "Deployment":
+		return (ref.Kind == "ReplicaSet" && !strings.Contains(suffix, "-")) ||
+			(ref.Kind == "Pod" && strings.Count(suffix, "-") == 1)
+	case "StatefulSet":
+		_, err := strconv.Atoi(suffix)
+		return ref.Kind == "Pod" && err == nil
+	case "DaemonSet":
+		return ref.Kind == "Pod" && !strings.Contains(suffix, "-")
+	}
+	return false
+}
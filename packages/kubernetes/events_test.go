@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"main/packages/models"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRefersTo(t *testing.T) {
+	deployment := models.InvolvedObject{Kind: "Deployment", Namespace: "shop", Name: "api"}
+	statefulSet := models.InvolvedObject{Kind: "StatefulSet", Namespace: "shop", Name: "db"}
+	daemonSet := models.InvolvedObject{Kind: "DaemonSet", Namespace: "kube-system", Name: "node-exporter"}
+	pod := models.InvolvedObject{Kind: "Pod", Namespace: "shop", Name: "api-7d9f8b6c5-x2k4p"}
+	node := models.InvolvedObject{Kind: "Node", Name: "worker-1"}
+
+	tests := []struct {
+		name   string
+		object models.InvolvedObject
+		ref    corev1.ObjectReference
+		want   bool
+	}{
+		{"deployment itself", deployment, corev1.ObjectReference{Kind: "Deployment", Namespace: "shop", Name: "api"}, true},
+		{"deployment replicaset", deployment, corev1.ObjectReference{Kind: "ReplicaSet", Namespace: "shop", Name: "api-7d9f8b6c5"}, true},
+		{"deployment pod", deployment, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "api-7d9f8b6c5-x2k4p"}, true},
+		{"deployment in another namespace", deployment, corev1.ObjectReference{Kind: "Deployment", Namespace: "staging", Name: "api"}, false},
+		{"pod of a deployment with a longer name", deployment, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "api-gateway-7d9f8b6c5-x2k4p"}, false},
+		{"replicaset of a deployment with a longer name", deployment, corev1.ObjectReference{Kind: "ReplicaSet", Namespace: "shop", Name: "api-gateway-7d9f8b6c5"}, false},
+		{"deployment name prefix without suffix", deployment, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "api-"}, false},
+		{"service named like the deployment", deployment, corev1.ObjectReference{Kind: "Service", Namespace: "shop", Name: "api"}, false},
+
+		{"statefulset pod", statefulSet, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "db-0"}, true},
+		{"statefulset pod with a high ordinal", statefulSet, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "db-12"}, true},
+		{"pod of another statefulset", statefulSet, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "db-backup-0"}, false},
+		{"statefulset pvc", statefulSet, corev1.ObjectReference{Kind: "PersistentVolumeClaim", Namespace: "shop", Name: "db-0"}, false},
+
+		{"daemonset pod", daemonSet, corev1.ObjectReference{Kind: "Pod", Namespace: "kube-system", Name: "node-exporter-8xkq2"}, true},
+		{"pod with a nested suffix", daemonSet, corev1.ObjectReference{Kind: "Pod", Namespace: "kube-system", Name: "node-exporter-extra-8xkq2"}, false},
+
+		{"pod itself", pod, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "api-7d9f8b6c5-x2k4p"}, true},
+		{"pod does not own other pods", pod, corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "api-7d9f8b6c5-x2k4p-1"}, false},
+
+		{"node ignores the namespace", node, corev1.ObjectReference{Kind: "Node", Namespace: "default", Name: "worker-1"}, true},
+		{"other node", node, corev1.ObjectReference{Kind: "Node", Name: "worker-10"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refersTo(tt.object, tt.ref); got != tt.want {
+				t.Errorf("refersTo(%s %s/%s, %s %s/%s) = %v, want %v",
+					tt.object.Kind, tt.object.Namespace, tt.object.Name, tt.ref.Kind, tt.ref.Namespace, tt.ref.Name, got, tt.want)
+			}
+		})
+	}
+}
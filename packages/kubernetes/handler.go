@@ -3,107 +3,163 @@ package kubernetes
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"main/packages/models"
 	"main/packages/utils"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
 )
 
-var (
-	clientset     *kubernetes.Clientset
-	dynamicClient dynamic.Interface
-)
-
-func InitKubernetesClients() error {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return err
-	}
-
-	clientset, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return err
-	}
-
-	dynamicClient, err = dynamic.NewForConfig(config)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // GET /pods
-// PodsGETHandler returns a list of pods
+// PodsGETHandler returns a list of pods, or their summaries with view=summary
 func PodsGETHandler(w http.ResponseWriter, r *http.Request) {
-	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		utils.WriteJSONError(w, "Failed to get pods", http.StatusInternalServerError)
-		log.Printf("Failed to get pods: %v", err)
+	namespace := namespaceParam(r)
+	opts, ok := listOptionsFromRequest(w, r)
+	if !ok {
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(pods.Items); err != nil {
-		log.Printf("JSON encoding error: %v", err)
+	view, ok := viewFromRequest(w, r)
+	if !ok {
+		return
 	}
+
+	listClusters(w, r, "Failed to get pods", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		pods, meta, err := listPods(c, r, namespace, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if view == viewSummary {
+			return listItems(SummarizePods(pods)), meta, nil
+		}
+		return listItems(pods), meta, nil
+	})
 }
 
 // GET /nodes
-// NodesGETHandler returns a list of nodes
+// NodesGETHandler returns a list of nodes, or their summaries with view=summary
 func NodesGETHandler(w http.ResponseWriter, r *http.Request) {
-	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		utils.WriteJSONError(w, "Failed to get nodes", http.StatusInternalServerError)
-		log.Printf("Failed to get nodes: %v", err)
+	opts, ok := clusterScopedListOptions(w, r, "nodes")
+	if !ok {
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(nodes.Items); err != nil {
-		log.Printf("JSON encoding error: %v", err)
+	view, ok := viewFromRequest(w, r)
+	if !ok {
+		return
 	}
+
+	listClusters(w, r, "Failed to get nodes", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		nodes, meta, err := listNodes(c, r, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if view == viewSummary {
+			return listItems(SummarizeNodes(nodes)), meta, nil
+		}
+		return listItems(nodes), meta, nil
+	})
 }
 
 // GET /namespaces
 // NamespacesGETHandler returns a list of namespaces
 func NamespacesGETHandler(w http.ResponseWriter, r *http.Request) {
-	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		utils.WriteJSONError(w, "Failed to get namespaces", http.StatusInternalServerError)
-		log.Printf("Failed to get namespaces: %v", err)
+	opts, ok := clusterScopedListOptions(w, r, "namespaces")
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(namespaces.Items); err != nil {
-		log.Printf("JSON encoding error: %v", err)
-	}
+	listClusters(w, r, "Failed to get namespaces", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		namespaces, meta, err := listNamespaces(c, r, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return listItems(namespaces), meta, nil
+	})
 }
 
 // GET /deployments
-// DeploymentsGETHandler returns a list of deployments
+// DeploymentsGETHandler returns a list of deployments, or their summaries with view=summary
 func DeploymentsGETHandler(w http.ResponseWriter, r *http.Request) {
-	deployments, err := clientset.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		utils.WriteJSONError(w, "Failed to get deployments", http.StatusInternalServerError)
-		log.Printf("Failed to get deployments: %v", err)
+	namespace := namespaceParam(r)
+	opts, ok := listOptionsFromRequest(w, r)
+	if !ok {
+		return
+	}
+	view, ok := viewFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(deployments.Items); err != nil {
-		log.Printf("JSON encoding error: %v", err)
+	listClusters(w, r, "Failed to get deployments", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		deployments, meta, err := listDeployments(c, r, namespace, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if view == viewSummary {
+			return listItems(SummarizeDeployments(deployments)), meta, nil
+		}
+		return listItems(deployments), meta, nil
+	})
+}
+
+// namespaceParam returns the namespace query parameter, where empty and "all" both mean every namespace
+func namespaceParam(r *http.Request) string {
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "all" {
+		return metav1.NamespaceAll
+	}
+	return namespace
+}
+
+// listOptionsFromRequest builds list options from the labelSelector, fieldSelector, limit and continue query
+// parameters, writing a 400 when one of them is malformed
+func listOptionsFromRequest(w http.ResponseWriter, r *http.Request) (metav1.ListOptions, bool) {
+	query := r.URL.Query()
+	opts := metav1.ListOptions{
+		LabelSelector: query.Get("labelSelector"),
+		FieldSelector: query.Get("fieldSelector"),
+		Continue:      query.Get("continue"),
+	}
+
+	if _, err := labels.Parse(opts.LabelSelector); err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid labelSelector: %v", err), http.StatusBadRequest)
+		return opts, false
+	}
+	if _, err := fields.ParseSelector(opts.FieldSelector); err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid fieldSelector: %v", err), http.StatusBadRequest)
+		return opts, false
 	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n <= 0 {
+			utils.WriteJSONError(w, "limit must be a positive number", http.StatusBadRequest)
+			return opts, false
+		}
+		opts.Limit = n
+	}
+	return opts, true
+}
+
+// clusterScopedListOptions is listOptionsFromRequest for kinds without namespaces, which reject the namespace parameter
+func clusterScopedListOptions(w http.ResponseWriter, r *http.Request, kind string) (metav1.ListOptions, bool) {
+	if namespaceParam(r) != "" {
+		utils.WriteJSONError(w, fmt.Sprintf("%s are not namespaced, the namespace parameter does not apply", kind), http.StatusBadRequest)
+		return metav1.ListOptions{}, false
+	}
+	return listOptionsFromRequest(w, r)
 }
 
 var prometheusRuleGVR = schema.GroupVersionResource{
@@ -126,102 +182,269 @@ func GetRulesHandler(w http.ResponseWriter, r *http.Request) {
 		namespace = ""
 	}
 
-	rules, err := dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	listRules(w, r, namespace)
+}
+
+// POST /rules
+// CreateRuleHandler creates a new PrometheusRule object
+func CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	namespace, _, _ := unstructured.NestedString(rule, "metadata", "namespace")
+	if namespace == "" {
+		utils.WriteJSONError(w, "Namespace is required in metadata", http.StatusBadRequest)
+		return
+	}
+
+	createRule(w, r, rule, namespace)
+}
+
+// PUT /rules/{id}
+// UpdateRuleHandler updates an existing PrometheusRule object
+func UpdateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteJSONError(w, "Rule ID is required in the URL", http.StatusBadRequest)
+		return
+	}
+
+	rule, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	namespace, _, _ := unstructured.NestedString(rule, "metadata", "namespace")
+	if namespace == "" {
+		utils.WriteJSONError(w, "Namespace is required in metadata", http.StatusBadRequest)
+		return
+	}
+
+	updateRule(w, r, rule, namespace, id)
+}
+
+// DELETE /rules/{id}
+// DeleteRuleHandler deletes a specific PrometheusRule object by its name
+func DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteJSONError(w, "Rule ID is required in the URL", http.StatusBadRequest)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		utils.WriteJSONError(w, "Namespace query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	deleteRule(w, r, namespace, id)
+}
+
+// GET /namespaces/{ns}/rules
+// ListNamespacedRulesHandler fetches the PrometheusRule objects of a namespace
+func ListNamespacedRulesHandler(w http.ResponseWriter, r *http.Request) {
+	listRules(w, r, r.PathValue("ns"))
+}
+
+// POST /namespaces/{ns}/rules
+// CreateNamespacedRuleHandler creates a new PrometheusRule object in the namespace from the path
+func CreateNamespacedRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	name, _, _ := unstructured.NestedString(rule, "metadata", "name")
+	if err := setObjectMetadata(rule, r.PathValue("ns"), name); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createRule(w, r, rule, r.PathValue("ns"))
+}
+
+// GET /namespaces/{ns}/rules/{name}
+// GetNamespacedRuleHandler fetches a single PrometheusRule object
+func GetNamespacedRuleHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	rule, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(r.PathValue("ns")).Get(context.TODO(), r.PathValue("name"), metav1.GetOptions{})
 	if err != nil {
-		utils.WriteJSONError(w, fmt.Sprintf("Failed to fetch PrometheusRule objects: %v", err), http.StatusInternalServerError)
-		log.Printf("Error fetching PrometheusRule objects: %v", err)
+		writeKubernetesError(w, "Failed to fetch PrometheusRule object", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(rules.Items); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
 		log.Printf("JSON encoding error: %v", err)
 	}
 }
 
-// POST /rules
-// CreateRuleHandler creates a new PrometheusRule object
-func CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
+// PUT /namespaces/{ns}/rules/{name}
+// UpdateNamespacedRuleHandler replaces a PrometheusRule object, its metadata must match the path
+func UpdateNamespacedRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
 
-	var rule map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
-		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
-		log.Printf("JSON decoding error: %v", err)
+	updateRule(w, r, rule, r.PathValue("ns"), r.PathValue("name"))
+}
+
+// PATCH /namespaces/{ns}/rules/{name}
+// PatchNamespacedRuleHandler applies a JSON merge patch, or a JSON patch with the application/json-patch+json
+// content type. The patched object is linted through a server-side dry run before it is written,
+// with dryRun=true that dry run is all that happens. A concurrent change of the rule returns 409.
+func PatchNamespacedRuleHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.WriteJSONError(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
-	metadata, ok := rule["metadata"].(map[string]interface{})
-	if !ok || metadata["namespace"] == "" {
-		utils.WriteJSONError(w, "Namespace is required in metadata", http.StatusBadRequest)
+	patchType := types.MergePatchType
+	if strings.HasPrefix(r.Header.Get("Content-Type"), string(types.JSONPatchType)) {
+		patchType = types.JSONPatchType
+	}
+
+	before, err := getRuleIfExists(c, r.PathValue("ns"), r.PathValue("name"))
+	if err != nil {
+		writeKubernetesError(w, "Failed to fetch PrometheusRule object", err)
+		return
+	}
+
+	resource := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(r.PathValue("ns"))
+	preview, err := resource.Patch(context.TODO(), r.PathValue("name"), patchType, patch, metav1.PatchOptions{DryRun: []string{metav1.DryRunAll}})
+	if err != nil {
+		writeKubernetesError(w, "Failed to patch PrometheusRule object", err)
+		return
+	}
+
+	if errs := ValidatePrometheusRule(preview.Object); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if dryRunOptions(r) != nil {
+		writeDryRunResult(w, before, preview)
+		return
+	}
+
+	// Write the validated preview rather than patching again: it carries the resourceVersion the dry run saw,
+	// so a change in between is rejected with 409 Conflict instead of writing an object that was never linted.
+	patchedRule, err := resource.Update(context.TODO(), preview, metav1.UpdateOptions{})
+	if err != nil {
+		writeKubernetesError(w, "Failed to patch PrometheusRule object", err)
+		return
+	}
+	recordRevision(c, r.PathValue("ns"), r.PathValue("name"), "update", utils.IdentityFromRequest(r), before, patchedRule)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(patchedRule); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// DELETE /namespaces/{ns}/rules/{name}
+// DeleteNamespacedRuleHandler deletes a single PrometheusRule object
+func DeleteNamespacedRuleHandler(w http.ResponseWriter, r *http.Request) {
+	deleteRule(w, r, r.PathValue("ns"), r.PathValue("name"))
+}
+
+func listRules(w http.ResponseWriter, r *http.Request, namespace string) {
+	opts, ok := listOptionsFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	namespace, ok := metadata["namespace"].(string)
-	if !ok || namespace == "" {
-		utils.WriteJSONError(w, "Namespace must be a non-empty string", http.StatusBadRequest)
+	listClusters(w, r, "Failed to fetch PrometheusRule objects", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		rules, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return listItems(rules.Items), rules, nil
+	})
+}
+
+func createRule(w http.ResponseWriter, r *http.Request, rule map[string]interface{}, namespace string) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	if errs := ValidatePrometheusRule(rule); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	createdRule, err := dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Create(
+	dryRun := dryRunOptions(r)
+	createdRule, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Create(
 		context.TODO(),
 		&unstructured.Unstructured{Object: rule},
-		metav1.CreateOptions{},
+		metav1.CreateOptions{DryRun: dryRun},
 	)
 	if err != nil {
-		utils.WriteJSONError(w, fmt.Sprintf("Failed to create PrometheusRule object: %v", err), http.StatusInternalServerError)
-		log.Printf("Error creating PrometheusRule: %v", err)
+		writeKubernetesError(w, "Failed to create PrometheusRule object", err)
 		return
 	}
+	if dryRun != nil {
+		writeDryRunResult(w, nil, createdRule)
+		return
+	}
+	recordRevision(c, namespace, createdRule.GetName(), "create", utils.IdentityFromRequest(r), nil, createdRule)
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(createdRule); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		log.Printf("JSON encoding error: %v", err)
 	}
 }
 
-// PUT /rules/{id}
-// UpdateRuleHandler updates an existing PrometheusRule object
-func UpdateRuleHandler(w http.ResponseWriter, r *http.Request) {
-
-	id := strings.TrimPrefix(r.URL.Path, "/rules/")
-	if id == "" {
-		utils.WriteJSONError(w, "Rule ID is required in the URL", http.StatusBadRequest)
+func updateRule(w http.ResponseWriter, r *http.Request, rule map[string]interface{}, namespace, name string) {
+	c, ok := requestCluster(w, r)
+	if !ok {
 		return
 	}
-
-	var rule map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
-		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
-		log.Printf("JSON decoding error: %v", err)
+	if err := setObjectMetadata(rule, namespace, name); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer r.Body.Close()
 
-	metadata, ok := rule["metadata"].(map[string]interface{})
-	if !ok || metadata["namespace"] == "" {
-		utils.WriteJSONError(w, "Namespace is required in metadata", http.StatusBadRequest)
+	if errs := ValidatePrometheusRule(rule); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	namespace, ok := metadata["namespace"].(string)
-	if !ok || namespace == "" {
-		utils.WriteJSONError(w, "Namespace must be a non-empty string", http.StatusBadRequest)
+	before, err := getRuleIfExists(c, namespace, name)
+	if err != nil {
+		writeKubernetesError(w, "Failed to fetch PrometheusRule object", err)
 		return
 	}
 
-	updatedRule, err := dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Update(
+	dryRun := dryRunOptions(r)
+	updatedRule, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Update(
 		context.TODO(),
 		&unstructured.Unstructured{Object: rule},
-		metav1.UpdateOptions{},
+		metav1.UpdateOptions{DryRun: dryRun},
 	)
 	if err != nil {
-		utils.WriteJSONError(w, fmt.Sprintf("Failed to update PrometheusRule object: %v", err), http.StatusInternalServerError)
-		log.Printf("Error updating PrometheusRule: %v", err)
+		writeKubernetesError(w, "Failed to update PrometheusRule object", err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, before, updatedRule)
 		return
 	}
+	recordRevision(c, namespace, name, "update", utils.IdentityFromRequest(r), before, updatedRule)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(updatedRule); err != nil {
@@ -230,28 +453,1262 @@ func UpdateRuleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// DELETE /rules/{id}
-// DeleteRuleHandler deletes a specific PrometheusRule object by its name
-func DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+func deleteRule(w http.ResponseWriter, r *http.Request, namespace, name string) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	before, err := getRuleIfExists(c, namespace, name)
+	if err != nil {
+		writeKubernetesError(w, "Failed to fetch PrometheusRule object", err)
+		return
+	}
 
-	id := strings.TrimPrefix(r.URL.Path, "/rules/")
-	if id == "" {
-		utils.WriteJSONError(w, "Rule ID is required in the URL", http.StatusBadRequest)
+	dryRun := dryRunOptions(r)
+	err = c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{DryRun: dryRun})
+	if err != nil {
+		writeKubernetesError(w, "Failed to delete PrometheusRule object", err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, before, nil)
 		return
 	}
+	recordRevision(c, namespace, name, "delete", utils.IdentityFromRequest(r), before, nil)
 
-	namespace := r.URL.Query().Get("namespace")
-	if namespace == "" {
-		utils.WriteJSONError(w, "Namespace query parameter is required", http.StatusBadRequest)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeObject reads a Kubernetes object as JSON from the request body, writing the error response on failure
+func decodeObject(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	var object map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&object); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		log.Printf("JSON decoding error: %v", err)
+		return nil, false
+	}
+	defer r.Body.Close()
+	return object, true
+}
+
+// setObjectMetadata makes sure the metadata of a namespaced object matches the namespace and name addressed
+// by the URL, filling them in when the body omits them
+func setObjectMetadata(object map[string]interface{}, namespace, name string) error {
+	for _, pair := range [][2]string{{"namespace", namespace}, {"name", name}} {
+		field, expected := pair[0], pair[1]
+		value, _, _ := unstructured.NestedString(object, "metadata", field)
+		if value != "" && value != expected {
+			return fmt.Errorf("metadata.%s %q does not match %q from the URL", field, value, expected)
+		}
+		if err := unstructured.SetNestedField(object, expected, "metadata", field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dryRunOptions returns the server-side dry run option when the request asks for dryRun=true, nil otherwise
+func dryRunOptions(r *http.Request) []string {
+	if r.URL.Query().Get("dryRun") == "true" {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// writeDryRunResult responds with the object computed by a dry run and its diff against the current object
+func writeDryRunResult(w http.ResponseWriter, before, after *unstructured.Unstructured) {
+	result := models.RuleDryRunResult{DryRun: true}
+	var previous map[string]interface{}
+	if before != nil {
+		previous = stripServerFields(before.Object)
+	}
+	if after != nil {
+		result.Object = after.Object
+		result.Diff = utils.DiffObjects(previous, stripServerFields(after.Object))
+	} else {
+		result.Diff = utils.DiffObjects(previous, nil)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// writeKubernetesError writes an error from the Kubernetes API with its status code, e.g. 404, 409 or 422
+func writeKubernetesError(w http.ResponseWriter, message string, err error) {
+	code := http.StatusInternalServerError
+	if status, ok := err.(apierrors.APIStatus); ok && status.Status().Code != 0 {
+		code = int(status.Status().Code)
+	}
+	utils.WriteJSONError(w, fmt.Sprintf("%s: %v", message, err), code)
+	log.Printf("%s: %v", message, err)
+}
+
+// POST /rules/validate
+// ValidateRuleHandler runs the same checks as create and update without writing to the cluster
+func ValidateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		log.Printf("JSON decoding error: %v", err)
 		return
 	}
+	defer r.Body.Close()
+
+	errs := ValidatePrometheusRule(rule)
+	response := models.RuleValidationResponse{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	}
+	if response.Errors == nil {
+		response.Errors = []models.RuleValidationError{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
 
-	err := dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Delete(context.TODO(), id, metav1.DeleteOptions{})
+// writeValidationErrors rejects a PrometheusRule with the per-rule validation errors
+func writeValidationErrors(w http.ResponseWriter, errs []models.RuleValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    false,
+		"error":      "PrometheusRule validation failed",
+		"statusCode": http.StatusUnprocessableEntity,
+		"errors":     errs,
+	})
+}
+
+// POST /rules/test
+// TestRuleHandler runs promtool-style unit tests against an inline or existing PrometheusRule.
+// The body may be sent as JSON or, with a YAML content type, in the promtool test file layout.
+func TestRuleHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRuleTestBodyBytes))
 	if err != nil {
-		utils.WriteJSONError(w, fmt.Sprintf("Failed to delete PrometheusRule object: %v", err), http.StatusInternalServerError)
-		log.Printf("Error deleting PrometheusRule: %v", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.WriteJSONError(w, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.WriteJSONError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		if body, err = yaml.YAMLToJSON(body); err != nil {
+			utils.WriteJSONError(w, fmt.Sprintf("Invalid YAML payload: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var request models.RuleTestRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		log.Printf("JSON decoding error: %v", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if len(request.Tests) == 0 {
+		utils.WriteJSONError(w, "At least one test is required", http.StatusBadRequest)
+		return
+	}
+
+	evalInterval := defaultEvaluationInterval
+	if request.EvaluationInterval != "" {
+		d, err := parseOptionalDuration(request.EvaluationInterval)
+		if err != nil || d <= 0 {
+			utils.WriteJSONError(w, "Invalid evaluation_interval", http.StatusBadRequest)
+			return
+		}
+		evalInterval = d
+	}
+	if err := ValidateRuleTestSteps(evalInterval, request.Tests); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := ValidateRuleTestSamples(request.Tests); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	rule := request.Rule
+	if rule == nil {
+		if request.Name == "" || request.Namespace == "" {
+			utils.WriteJSONError(w, "Either rule or name and namespace are required", http.StatusBadRequest)
+			return
+		}
+
+		if !KubernetesAvailable() {
+			utils.WriteJSONError(w, ErrorKubernetesUnavailable.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		c, ok := requestCluster(w, r)
+		if !ok {
+			return
+		}
+		existing, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(request.Namespace).Get(context.TODO(), request.Name, metav1.GetOptions{})
+		if err != nil {
+			writeKubernetesError(w, "Failed to fetch PrometheusRule object", err)
+			return
+		}
+		rule = existing.Object
+	}
+
+	spec, err := ParseRuleSpec(rule)
+	if err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid PrometheusRule spec: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(RunRuleTests(spec, evalInterval, request.Tests)); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// POST /rules/backtest
+// BacktestRuleHandler replays an alert rule against historical Prometheus data
+func BacktestRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.BacktestRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	result, err := Backtest(request)
+	if err != nil {
+		code := http.StatusBadGateway
+		if errors.Is(err, ErrorInvalidBacktest) {
+			code = http.StatusBadRequest
+		}
+		utils.WriteJSONError(w, fmt.Sprintf("Error running backtest: %v", err), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// POST /namespaces/{ns}/rules/{name}/groups/{group}/alerts
+// AddAlertingRuleHandler appends a single alerting rule to a group of a PrometheusRule
+func AddAlertingRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule models.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		log.Printf("JSON decoding error: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	if rule.Alert == "" {
+		utils.WriteJSONError(w, "Alert name is required", http.StatusBadRequest)
+		return
+	}
+
+	editAlertingRule(w, r, rule.Alert, ruleEditAdd, &rule)
+}
+
+// PUT /namespaces/{ns}/rules/{name}/groups/{group}/alerts/{alert}
+// UpdateAlertingRuleHandler replaces a single alerting rule inside a group of a PrometheusRule
+func UpdateAlertingRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule models.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		log.Printf("JSON decoding error: %v", err)
+		return
+	}
+	defer r.Body.Close()
+
+	editAlertingRule(w, r, r.PathValue("alert"), ruleEditUpdate, &rule)
+}
+
+// DELETE /namespaces/{ns}/rules/{name}/groups/{group}/alerts/{alert}
+// DeleteAlertingRuleHandler removes a single alerting rule from a group of a PrometheusRule
+func DeleteAlertingRuleHandler(w http.ResponseWriter, r *http.Request) {
+	editAlertingRule(w, r, r.PathValue("alert"), ruleEditRemove, nil)
+}
+
+// editAlertingRule applies a single-rule edit. The expected resourceVersion is taken from the
+// resourceVersion query parameter or the If-Match header, a stale one results in 409 Conflict.
+func editAlertingRule(w http.ResponseWriter, r *http.Request, alert string, op ruleEditOp, rule *models.Rule) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	resourceVersion := r.URL.Query().Get("resourceVersion")
+	if resourceVersion == "" {
+		resourceVersion = strings.Trim(r.Header.Get("If-Match"), `"`)
+	}
+
+	dryRun := dryRunOptions(r)
+	before, patched, errs, err := PatchAlertingRule(c, r.PathValue("ns"), r.PathValue("name"), r.PathValue("group"), alert, resourceVersion, utils.IdentityFromRequest(r), dryRun, op, rule)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorRuleGroupNotFound), errors.Is(err, ErrorAlertNotFound):
+			utils.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrorAlertExists):
+			utils.WriteJSONError(w, err.Error(), http.StatusConflict)
+		default:
+			writeKubernetesError(w, fmt.Sprintf("Failed to %s alerting rule", op), err)
+		}
+		return
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, before, patched)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if op == ruleEditAdd {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(patched); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// GET /namespaces/{ns}/rules/{name}/revisions
+// RuleRevisionsGETHandler lists the recorded revisions of a PrometheusRule, newest first
+func RuleRevisionsGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := store.GetRuleRevisions(c.Name, r.PathValue("ns"), r.PathValue("name"))
+	if err != nil {
+		utils.WriteJSONError(w, "Failed to retrieve revisions", http.StatusInternalServerError)
+		log.Printf("Failed to retrieve revisions: %v", err)
+		return
+	}
+
+	// The listing carries the diffs only, full snapshots are served per revision
+	for i := range revisions {
+		revisions[i].Previous = nil
+		revisions[i].Current = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// GET /namespaces/{ns}/rules/{name}/revisions/{revision}
+// RuleRevisionGETHandler returns a single revision including the full snapshots
+func RuleRevisionGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	revision, err := strconv.Atoi(r.PathValue("revision"))
+	if err != nil {
+		utils.WriteJSONError(w, "Revision must be a number", http.StatusBadRequest)
+		return
+	}
+
+	stored, err := GetRuleRevision(c, r.PathValue("ns"), r.PathValue("name"), revision)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stored); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// GET /namespaces/{ns}/rules/{name}/revisions/diff?from=&to=
+// RuleRevisionsDiffGETHandler compares the objects recorded after two revisions
+func RuleRevisionsDiffGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		utils.WriteJSONError(w, "from query parameter must be a revision number", http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		utils.WriteJSONError(w, "to query parameter must be a revision number", http.StatusBadRequest)
+		return
+	}
+
+	fromRevision, err := GetRuleRevision(c, r.PathValue("ns"), r.PathValue("name"), from)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	toRevision, err := GetRuleRevision(c, r.PathValue("ns"), r.PathValue("name"), to)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"diff": utils.DiffObjects(fromRevision.Current, toRevision.Current),
+	})
+}
+
+// POST /namespaces/{ns}/rules/{name}/rollback?revision=
+// RollbackRuleHandler restores a PrometheusRule to the state recorded after a revision, validated like an update
+func RollbackRuleHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	revision, err := strconv.Atoi(r.URL.Query().Get("revision"))
+	if err != nil {
+		utils.WriteJSONError(w, "revision query parameter must be a number", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := dryRunOptions(r)
+	before, restored, errs, err := RollbackRule(c, r.PathValue("ns"), r.PathValue("name"), revision, utils.IdentityFromRequest(r), dryRun)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, before, restored)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(restored); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+func writeRevisionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrorRevisionNotFound):
+		utils.WriteJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrorRevisionDeleted):
+		utils.WriteJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		writeKubernetesError(w, "Failed to process revision", err)
+	}
+}
+
+// GET /rules/export
+// ExportRulesHandler returns apply-ready PrometheusRules as multi-document YAML,
+// or with format=tar as an archive holding one file per namespace
+func ExportRulesHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "all" {
+		namespace = ""
+	}
+
+	rules, err := ExportRules(c, namespace)
+	if err != nil {
+		writeKubernetesError(w, "Failed to fetch PrometheusRule objects", err)
+		return
+	}
+
+	var data []byte
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "yaml":
+		data, err = EncodeRulesYAML(rules)
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("Content-Disposition", `attachment; filename="prometheusrules.yaml"`)
+	case "tar":
+		data, err = EncodeRulesTar(rules)
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", `attachment; filename="prometheusrules.tar"`)
+	default:
+		utils.WriteJSONError(w, fmt.Sprintf("Unsupported format %q", format), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		utils.WriteJSONError(w, fmt.Sprintf("Failed to encode PrometheusRule objects: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
+
+// POST /rules/import
+// ImportRulesHandler applies a YAML or JSON bundle of PrometheusRules, with dryRun=true nothing is written
+func ImportRulesHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	bundle, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.WriteJSONError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	result, err := ImportRules(c, bundle, r.URL.Query().Get("namespace"), dryRun, utils.IdentityFromRequest(r))
+	if err != nil {
+		utils.WriteJSONError(w, fmt.Sprintf("Invalid bundle: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// GET /rule-templates
+// RuleTemplatesGETHandler returns the built-in and user-defined rule templates
+func RuleTemplatesGETHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := ListRuleTemplates()
+	if err != nil {
+		writeKubernetesError(w, "Failed to load rule templates", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(templates); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// GET /rule-templates/{name}
+// RuleTemplateGETHandler returns a single rule template
+func RuleTemplateGETHandler(w http.ResponseWriter, r *http.Request) {
+	t, err := GetRuleTemplate(r.PathValue("name"))
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// POST /rule-templates/{name}/render
+// RenderRuleTemplateHandler renders a template into a PrometheusRule object ready to be sent to POST /rules.
+// Nothing is created; the rendered object is linted and validation problems are returned as 422.
+func RenderRuleTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.RuleTemplateRenderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	t, err := GetRuleTemplate(r.PathValue("name"))
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	rule, err := RenderRuleTemplate(t, request)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+	if errs := ValidatePrometheusRule(rule); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+func writeTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrorTemplateNotFound):
+		utils.WriteJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrorInvalidRenderParams):
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrorInvalidTemplate):
+		utils.WriteJSONError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		writeKubernetesError(w, "Failed to load rule templates", err)
+	}
+}
+
+// GET /slos
+// SLOsGETHandler returns the SLOs of the namespace query parameter, or of all namespaces
+func SLOsGETHandler(w http.ResponseWriter, r *http.Request) {
+	listClusters(w, r, "Failed to fetch SLOs", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		slos, err := ListSLOs(c, r.URL.Query().Get("namespace"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return listItems(slos), nil, nil
+	})
+}
+
+// GET /slos/{ns}/{name}
+// SLOGETHandler returns a single SLO
+func SLOGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	slo, err := GetSLO(c, r.PathValue("ns"), r.PathValue("name"))
+	if err != nil {
+		writeSLOError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(slo); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// POST /slos
+// CreateSLOHandler declares a new SLO and generates its PrometheusRule
+func CreateSLOHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	var slo models.SLO
+	if err := json.NewDecoder(r.Body).Decode(&slo); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	dryRun := dryRunOptions(r)
+	created, err := CreateSLO(c, slo, utils.IdentityFromRequest(r), dryRun)
+	if err != nil {
+		writeSLOError(w, err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, nil, created)
+		return
+	}
+	writeSLO(w, created, http.StatusCreated)
+}
+
+// PUT /slos/{ns}/{name}
+// UpdateSLOHandler replaces an SLO and regenerates its PrometheusRule
+func UpdateSLOHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	var slo models.SLO
+	if err := json.NewDecoder(r.Body).Decode(&slo); err != nil {
+		utils.WriteJSONError(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	for _, pair := range [][3]string{{"namespace", slo.Namespace, r.PathValue("ns")}, {"name", slo.Name, r.PathValue("name")}} {
+		if pair[1] != "" && pair[1] != pair[2] {
+			utils.WriteJSONError(w, fmt.Sprintf("%s %q does not match %q from the URL", pair[0], pair[1], pair[2]), http.StatusBadRequest)
+			return
+		}
+	}
+	slo.Namespace, slo.Name = r.PathValue("ns"), r.PathValue("name")
+
+	dryRun := dryRunOptions(r)
+	before, updated, err := UpdateSLO(c, slo, utils.IdentityFromRequest(r), dryRun)
+	if err != nil {
+		writeSLOError(w, err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, before, updated)
+		return
+	}
+	writeSLO(w, updated, http.StatusOK)
+}
+
+// DELETE /slos/{ns}/{name}
+// DeleteSLOHandler removes an SLO together with its generated PrometheusRule
+func DeleteSLOHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	dryRun := dryRunOptions(r)
+	before, err := DeleteSLO(c, r.PathValue("ns"), r.PathValue("name"), utils.IdentityFromRequest(r), dryRun)
+	if err != nil {
+		writeSLOError(w, err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, before, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSLO responds with the SLO definition stored on a generated PrometheusRule
+func writeSLO(w http.ResponseWriter, rule *unstructured.Unstructured, code int) {
+	slo, err := sloFromRule(rule)
+	if err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(slo); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+func writeSLOError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrorSLONotFound):
+		utils.WriteJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrorInvalidSLO):
+		utils.WriteJSONError(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrorNotSLOManaged):
+		utils.WriteJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		writeKubernetesError(w, "Failed to process SLO", err)
+	}
+}
+
+// GET /monitors/{kind}
+// MonitorsGETHandler returns the ServiceMonitors or PodMonitors of the namespace query parameter, or of all namespaces
+func MonitorsGETHandler(w http.ResponseWriter, r *http.Request) {
+	listMonitors(w, r, r.URL.Query().Get("namespace"))
+}
+
+// GET /namespaces/{ns}/monitors/{kind}
+// NamespacedMonitorsGETHandler returns the ServiceMonitors or PodMonitors of a namespace
+func NamespacedMonitorsGETHandler(w http.ResponseWriter, r *http.Request) {
+	listMonitors(w, r, r.PathValue("ns"))
+}
+
+func listMonitors(w http.ResponseWriter, r *http.Request, namespace string) {
+	kind, ok := monitorKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	listClusters(w, r, fmt.Sprintf("Failed to fetch %s objects", kind.Kind), func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		monitors, err := ListMonitors(c, kind, namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+		return listItems(monitors), nil, nil
+	})
+}
+
+// GET /namespaces/{ns}/monitors/{kind}/{name}
+// MonitorGETHandler returns a single ServiceMonitor or PodMonitor
+func MonitorGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	kind, ok := monitorKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	monitor, err := c.dynamicClient.Resource(kind.GVR).Namespace(r.PathValue("ns")).Get(context.TODO(), r.PathValue("name"), metav1.GetOptions{})
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to fetch %s object", kind.Kind), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(monitor); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// POST /namespaces/{ns}/monitors/{kind}
+// CreateMonitorHandler creates a ServiceMonitor or PodMonitor after validating it
+func CreateMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	kind, ok := monitorKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+	monitor, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	namespace := r.PathValue("ns")
+	name, _, _ := unstructured.NestedString(monitor, "metadata", "name")
+	if err := setMonitorMetadata(kind, monitor, namespace, name); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errs := ValidateMonitor(kind, monitor); len(errs) > 0 {
+		writeObjectValidationErrors(w, kind.Kind, errs)
+		return
+	}
+
+	dryRun := dryRunOptions(r)
+	created, err := c.dynamicClient.Resource(kind.GVR).Namespace(namespace).Create(
+		context.TODO(),
+		&unstructured.Unstructured{Object: monitor},
+		metav1.CreateOptions{DryRun: dryRun},
+	)
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to create %s object", kind.Kind), err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, nil, created)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// PUT /namespaces/{ns}/monitors/{kind}/{name}
+// UpdateMonitorHandler replaces a ServiceMonitor or PodMonitor after validating it
+func UpdateMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	kind, ok := monitorKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+	monitor, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	namespace, name := r.PathValue("ns"), r.PathValue("name")
+	if err := setMonitorMetadata(kind, monitor, namespace, name); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errs := ValidateMonitor(kind, monitor); len(errs) > 0 {
+		writeObjectValidationErrors(w, kind.Kind, errs)
+		return
+	}
+
+	current, err := c.dynamicClient.Resource(kind.GVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to fetch %s object", kind.Kind), err)
+		return
+	}
+	object := &unstructured.Unstructured{Object: monitor}
+	if object.GetResourceVersion() == "" {
+		object.SetResourceVersion(current.GetResourceVersion())
+	}
+
+	dryRun := dryRunOptions(r)
+	updated, err := c.dynamicClient.Resource(kind.GVR).Namespace(namespace).Update(context.TODO(), object, metav1.UpdateOptions{DryRun: dryRun})
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to update %s object", kind.Kind), err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, current, updated)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// DELETE /namespaces/{ns}/monitors/{kind}/{name}
+// DeleteMonitorHandler deletes a ServiceMonitor or PodMonitor
+func DeleteMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	kind, ok := monitorKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	namespace, name := r.PathValue("ns"), r.PathValue("name")
+	dryRun := dryRunOptions(r)
+	var current *unstructured.Unstructured
+	if dryRun != nil {
+		var err error
+		current, err = c.dynamicClient.Resource(kind.GVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			writeKubernetesError(w, fmt.Sprintf("Failed to fetch %s object", kind.Kind), err)
+			return
+		}
+	}
+
+	err := c.dynamicClient.Resource(kind.GVR).Namespace(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{DryRun: dryRun})
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to delete %s object", kind.Kind), err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, current, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /monitors/{kind}/validate
+// ValidateMonitorHandler runs the same checks as create and update without writing to the cluster
+func ValidateMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	kind, ok := monitorKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+	monitor, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	errs := ValidateMonitor(kind, monitor)
+	response := models.MonitorValidationResponse{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	}
+	if response.Errors == nil {
+		response.Errors = []models.MonitorValidationError{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// GET /namespaces/{ns}/monitors/{kind}/{name}/check
+// MonitorCheckGETHandler explains whether a monitor selects any Service or Pod and whether Prometheus scrapes it
+func MonitorCheckGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	kind, ok := monitorKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	check, err := CheckMonitor(c, kind, r.PathValue("ns"), r.PathValue("name"))
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to check %s", kind.Kind), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(check); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// monitorKindFromRequest resolves the {kind} path segment, writing a 404 for anything but servicemonitors and podmonitors
+func monitorKindFromRequest(w http.ResponseWriter, r *http.Request) (monitorKind, bool) {
+	kind, err := lookupMonitorKind(r.PathValue("kind"))
+	if err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return kind, false
+	}
+	return kind, true
+}
+
+// setMonitorMetadata fills in the type information and makes the metadata match the path
+func setMonitorMetadata(kind monitorKind, monitor map[string]interface{}, namespace, name string) error {
+	if err := setObjectMetadata(monitor, namespace, name); err != nil {
+		return err
+	}
+	monitor["apiVersion"] = kind.GVR.GroupVersion().String()
+	monitor["kind"] = kind.Kind
+	return nil
+}
+
+// writeObjectValidationErrors rejects an object of the given kind with its validation errors
+func writeObjectValidationErrors(w http.ResponseWriter, kind string, errs interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    false,
+		"error":      kind + " validation failed",
+		"statusCode": http.StatusUnprocessableEntity,
+		"errors":     errs,
+	})
+}
+
+// GET /alertmanagerconfigs
+// AlertmanagerConfigsGETHandler returns the AlertmanagerConfigs of the namespace query parameter,
+// or of every namespace the caller may access
+func AlertmanagerConfigsGETHandler(w http.ResponseWriter, r *http.Request) {
+	namespaces := []string{metav1.NamespaceAll}
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		if !utils.CanAccessNamespace(r, namespace) {
+			writeNamespaceForbidden(w, namespace)
+			return
+		}
+		namespaces = []string{namespace}
+	} else if allowed, all := utils.AllowedNamespaces(r); !all {
+		namespaces = allowed
+	}
+
+	listAlertmanagerConfigs(w, r, namespaces)
+}
+
+// GET /namespaces/{ns}/alertmanagerconfigs
+// NamespacedAlertmanagerConfigsGETHandler returns the AlertmanagerConfigs of a namespace
+func NamespacedAlertmanagerConfigsGETHandler(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("ns")
+	if !utils.CanAccessNamespace(r, namespace) {
+		writeNamespaceForbidden(w, namespace)
+		return
+	}
+
+	listAlertmanagerConfigs(w, r, []string{namespace})
+}
+
+func listAlertmanagerConfigs(w http.ResponseWriter, r *http.Request, namespaces []string) {
+	listClusters(w, r, "Failed to fetch AlertmanagerConfig objects", func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		configs, err := ListAlertmanagerConfigs(c, namespaces)
+		if err != nil {
+			return nil, nil, err
+		}
+		return listItems(configs), nil, nil
+	})
+}
+
+// GET /namespaces/{ns}/alertmanagerconfigs/{name}
+// AlertmanagerConfigGETHandler returns a single AlertmanagerConfig
+func AlertmanagerConfigGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	namespace := r.PathValue("ns")
+	if !utils.CanAccessNamespace(r, namespace) {
+		writeNamespaceForbidden(w, namespace)
+		return
+	}
+
+	config, err := c.dynamicClient.Resource(alertmanagerConfigGVR).Namespace(namespace).Get(context.TODO(), r.PathValue("name"), metav1.GetOptions{})
+	if err != nil {
+		writeKubernetesError(w, "Failed to fetch AlertmanagerConfig object", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(config); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// POST /namespaces/{ns}/alertmanagerconfigs
+// CreateAlertmanagerConfigHandler creates an AlertmanagerConfig after validating its routes, receivers and matchers
+func CreateAlertmanagerConfigHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	namespace := r.PathValue("ns")
+	if !utils.CanAccessNamespace(r, namespace) {
+		writeNamespaceForbidden(w, namespace)
+		return
+	}
+	config, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	name, _, _ := unstructured.NestedString(config, "metadata", "name")
+	if err := setAlertmanagerConfigMetadata(config, namespace, name); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errs := ValidateAlertmanagerConfig(config); len(errs) > 0 {
+		writeObjectValidationErrors(w, "AlertmanagerConfig", errs)
+		return
+	}
+
+	dryRun := dryRunOptions(r)
+	created, err := c.dynamicClient.Resource(alertmanagerConfigGVR).Namespace(namespace).Create(
+		context.TODO(),
+		&unstructured.Unstructured{Object: config},
+		metav1.CreateOptions{DryRun: dryRun},
+	)
+	if err != nil {
+		writeKubernetesError(w, "Failed to create AlertmanagerConfig object", err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, nil, created)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// PUT /namespaces/{ns}/alertmanagerconfigs/{name}
+// UpdateAlertmanagerConfigHandler replaces an AlertmanagerConfig after validating it
+func UpdateAlertmanagerConfigHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	namespace, name := r.PathValue("ns"), r.PathValue("name")
+	if !utils.CanAccessNamespace(r, namespace) {
+		writeNamespaceForbidden(w, namespace)
+		return
+	}
+	config, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	if err := setAlertmanagerConfigMetadata(config, namespace, name); err != nil {
+		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errs := ValidateAlertmanagerConfig(config); len(errs) > 0 {
+		writeObjectValidationErrors(w, "AlertmanagerConfig", errs)
+		return
+	}
+
+	current, err := c.dynamicClient.Resource(alertmanagerConfigGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		writeKubernetesError(w, "Failed to fetch AlertmanagerConfig object", err)
+		return
+	}
+	object := &unstructured.Unstructured{Object: config}
+	if object.GetResourceVersion() == "" {
+		object.SetResourceVersion(current.GetResourceVersion())
+	}
+
+	dryRun := dryRunOptions(r)
+	updated, err := c.dynamicClient.Resource(alertmanagerConfigGVR).Namespace(namespace).Update(context.TODO(), object, metav1.UpdateOptions{DryRun: dryRun})
+	if err != nil {
+		writeKubernetesError(w, "Failed to update AlertmanagerConfig object", err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, current, updated)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// DELETE /namespaces/{ns}/alertmanagerconfigs/{name}
+// DeleteAlertmanagerConfigHandler deletes an AlertmanagerConfig
+func DeleteAlertmanagerConfigHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	namespace, name := r.PathValue("ns"), r.PathValue("name")
+	if !utils.CanAccessNamespace(r, namespace) {
+		writeNamespaceForbidden(w, namespace)
+		return
+	}
+
+	dryRun := dryRunOptions(r)
+	var current *unstructured.Unstructured
+	if dryRun != nil {
+		var err error
+		current, err = c.dynamicClient.Resource(alertmanagerConfigGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			writeKubernetesError(w, "Failed to fetch AlertmanagerConfig object", err)
+			return
+		}
+	}
+
+	err := c.dynamicClient.Resource(alertmanagerConfigGVR).Namespace(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{DryRun: dryRun})
+	if err != nil {
+		writeKubernetesError(w, "Failed to delete AlertmanagerConfig object", err)
+		return
+	}
+	if dryRun != nil {
+		writeDryRunResult(w, current, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /alertmanagerconfigs/validate
+// ValidateAlertmanagerConfigHandler runs the same checks as create and update without writing to the cluster
+func ValidateAlertmanagerConfigHandler(w http.ResponseWriter, r *http.Request) {
+	config, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+
+	errs := ValidateAlertmanagerConfig(config)
+	response := models.AlertmanagerConfigValidationResponse{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	}
+	if response.Errors == nil {
+		response.Errors = []models.AlertmanagerConfigValidationError{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// setAlertmanagerConfigMetadata fills in the type information and makes the metadata match the path
+func setAlertmanagerConfigMetadata(config map[string]interface{}, namespace, name string) error {
+	if err := setObjectMetadata(config, namespace, name); err != nil {
+		return err
+	}
+	config["apiVersion"] = alertmanagerConfigGVR.GroupVersion().String()
+	config["kind"] = "AlertmanagerConfig"
+	return nil
+}
+
+func writeNamespaceForbidden(w http.ResponseWriter, namespace string) {
+	utils.WriteJSONError(w, fmt.Sprintf("%v: %s", ErrorNamespaceForbidden, namespace), http.StatusForbidden)
 }
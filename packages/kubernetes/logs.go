@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"main/packages/config"
+	"main/packages/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultPodLogsMaxBytes caps a log response when POD_LOGS_MAX_BYTES is not set
+const defaultPodLogsMaxBytes = 10 << 20
+
+var podLogsMaxBytes = parsePodLogsMaxBytes(config.GetEnv("POD_LOGS_MAX_BYTES", ""))
+
+func parsePodLogsMaxBytes(value string) int64 {
+	if value == "" {
+		return defaultPodLogsMaxBytes
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		log.Printf("Ignoring invalid POD_LOGS_MAX_BYTES %q, using %d", value, defaultPodLogsMaxBytes)
+		return defaultPodLogsMaxBytes
+	}
+	return n
+}
+
+// podLogOptionsFromRequest reads the log options from the query, writing a 400 for invalid values.
+// limitBytes can only lower the POD_LOGS_MAX_BYTES limit, which applies to every response.
+func podLogOptionsFromRequest(w http.ResponseWriter, r *http.Request) (*corev1.PodLogOptions, bool) {
+	query := r.URL.Query()
+	opts := &corev1.PodLogOptions{
+		Container:  query.Get("container"),
+		Previous:   query.Get("previous") == "true",
+		Follow:     query.Get("follow") == "true",
+		Timestamps: query.Get("timestamps") == "true",
+	}
+
+	limit := podLogsMaxBytes
+	if value := query.Get("limitBytes"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			utils.WriteJSONError(w, "limitBytes must be a positive number", http.StatusBadRequest)
+			return nil, false
+		}
+		limit = min(limit, n)
+	}
+	opts.LimitBytes = &limit
+
+	if value := query.Get("tailLines"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			utils.WriteJSONError(w, "tailLines must be a non-negative number", http.StatusBadRequest)
+			return nil, false
+		}
+		opts.TailLines = &n
+	}
+	if value := query.Get("sinceTime"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.WriteJSONError(w, fmt.Sprintf("Invalid sinceTime %q, expected an RFC 3339 timestamp", value), http.StatusBadRequest)
+			return nil, false
+		}
+		opts.SinceTime = &metav1.Time{Time: since}
+	}
+	return opts, true
+}
+
+// GET /namespaces/{ns}/pods/{pod}/logs
+// PodLogsGETHandler returns the logs of a pod container as plain text. With follow=true the logs are streamed
+// with chunked encoding until the client disconnects, the container stops or the size limit is reached.
+func PodLogsGETHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+	namespace := r.PathValue("ns")
+	if !utils.CanAccessNamespace(r, namespace) {
+		writeNamespaceForbidden(w, namespace)
+		return
+	}
+	opts, ok := podLogOptionsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	stream, err := c.clientset.CoreV1().Pods(namespace).GetLogs(r.PathValue("pod"), opts).Stream(r.Context())
+	if err != nil {
+		writeKubernetesError(w, "Failed to get pod logs", err)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Limit-Bytes", strconv.FormatInt(*opts.LimitBytes, 10))
+	if !opts.Follow {
+		if _, err := io.Copy(w, stream); err != nil {
+			log.Printf("Failed to copy logs of pod %s/%s: %v", namespace, r.PathValue("pod"), err)
+		}
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear the write deadline of a log stream: %v", err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	controller.Flush()
+
+	buffer := make([]byte, 32<<10)
+	for {
+		n, err := stream.Read(buffer)
+		if n > 0 {
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return
+			}
+			controller.Flush()
+		}
+		if err != nil {
+			if err != io.EOF && r.Context().Err() == nil {
+				log.Printf("Log stream of pod %s/%s ended: %v", namespace, r.PathValue("pod"), err)
+			}
+			return
+		}
+	}
+}
+
+// GET /alerts/{fingerprint}/logs
+// AlertLogsGETHandler redirects to the logs of the pod named in the labels of a stored alert, starting the lookback
+// duration (15m by default) before the alert started. A container label selects the container; the log parameters
+// of the request are passed on and take precedence.
+func AlertLogsGETHandler(w http.ResponseWriter, r *http.Request) {
+	lookback, ok := lookbackFromRequest(w, r)
+	if !ok {
+		return
+	}
+	alert, ok := storedAlert(w, r)
+	if !ok {
+		return
+	}
+	namespace, pod := alert.Labels["namespace"], alert.Labels["pod"]
+	if namespace == "" || pod == "" {
+		utils.WriteJSONError(w, fmt.Sprintf("%v: %s", ErrorAlertWithoutPod, alert.Fingerprint), http.StatusNotFound)
+		return
+	}
+	c, ok := alertCluster(w, r, alert.Labels)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	query.Del("lookback")
+	query.Set("cluster", c.Name)
+	if query.Get("container") == "" && alert.Labels["container"] != "" {
+		query.Set("container", alert.Labels["container"])
+	}
+	if query.Get("sinceTime") == "" {
+		query.Set("sinceTime", alert.StartsAt.Add(-lookback).UTC().Format(time.RFC3339))
+	}
+
+	location := fmt.Sprintf("/namespaces/%s/pods/%s/logs?%s", url.PathEscape(namespace), url.PathEscape(pod), query.Encode())
+	http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+}
@@ -0,0 +1,435 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"main/packages/models"
+	"main/packages/utils"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// monitorKind describes one of the prometheus-operator scrape configuration resources
+type monitorKind struct {
+	Resource string
+	Kind     string
+	GVR      schema.GroupVersionResource
+	// Target is the kind of object the selector picks
+	Target string
+	// ScrapePool is the prefix the operator gives the scrape pools generated for the monitor
+	ScrapePool     string
+	EndpointsField string
+}
+
+var monitorKinds = map[string]monitorKind{
+	"servicemonitors": {
+		Resource:       "servicemonitors",
+		Kind:           "ServiceMonitor",
+		GVR:            schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"},
+		Target:         "Service",
+		ScrapePool:     "serviceMonitor",
+		EndpointsField: "endpoints",
+	},
+	"podmonitors": {
+		Resource:       "podmonitors",
+		Kind:           "PodMonitor",
+		GVR:            schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "podmonitors"},
+		Target:         "Pod",
+		ScrapePool:     "podMonitor",
+		EndpointsField: "podMetricsEndpoints",
+	},
+}
+
+// relabelActions are the relabeling actions understood by Prometheus, compared case-insensitively
+var relabelActions = []string{"replace", "keep", "drop", "keepequal", "dropequal", "hashmod", "labelmap", "labeldrop", "labelkeep", "lowercase", "uppercase"}
+
+// monitorSpec holds the parts of a ServiceMonitor or PodMonitor spec the agent checks
+type monitorSpec struct {
+	Selector          *metav1.LabelSelector `json:"selector"`
+	NamespaceSelector struct {
+		Any        bool     `json:"any"`
+		MatchNames []string `json:"matchNames"`
+	} `json:"namespaceSelector"`
+	Endpoints           []monitorEndpoint `json:"endpoints"`
+	PodMetricsEndpoints []monitorEndpoint `json:"podMetricsEndpoints"`
+}
+
+type monitorEndpoint struct {
+	Port              string              `json:"port"`
+	PortNumber        *int32              `json:"portNumber"`
+	TargetPort        *intstr.IntOrString `json:"targetPort"`
+	Path              string              `json:"path"`
+	Scheme            string              `json:"scheme"`
+	Interval          string              `json:"interval"`
+	ScrapeTimeout     string              `json:"scrapeTimeout"`
+	Relabelings       []relabelConfig     `json:"relabelings"`
+	MetricRelabelings []relabelConfig     `json:"metricRelabelings"`
+}
+
+type relabelConfig struct {
+	Action string `json:"action"`
+	Regex  string `json:"regex"`
+}
+
+// name identifies the endpoint port in messages
+func (e monitorEndpoint) name() string {
+	switch {
+	case e.Port != "":
+		return e.Port
+	case e.PortNumber != nil:
+		return strconv.Itoa(int(*e.PortNumber))
+	case e.TargetPort != nil:
+		return e.TargetPort.String()
+	}
+	return ""
+}
+
+func lookupMonitorKind(resource string) (monitorKind, error) {
+	kind, ok := monitorKinds[resource]
+	if !ok {
+		return kind, fmt.Errorf("%w: %q", ErrorUnknownMonitorKind, resource)
+	}
+	return kind, nil
+}
+
+func parseMonitorSpec(kind monitorKind, monitor map[string]interface{}) (monitorSpec, []monitorEndpoint, error) {
+	var spec monitorSpec
+	rawSpec, ok := monitor["spec"]
+	if !ok {
+		return spec, nil, fmt.Errorf("spec is required")
+	}
+	data, err := json.Marshal(rawSpec)
+	if err != nil {
+		return spec, nil, err
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return spec, nil, err
+	}
+
+	if kind.EndpointsField == "podMetricsEndpoints" {
+		return spec, spec.PodMetricsEndpoints, nil
+	}
+	return spec, spec.Endpoints, nil
+}
+
+// ValidateMonitor lints a ServiceMonitor or PodMonitor object and returns every problem found, or nil if it is valid
+func ValidateMonitor(kind monitorKind, monitor map[string]interface{}) []models.MonitorValidationError {
+	var errs []models.MonitorValidationError
+	fail := func(field, message string) {
+		errs = append(errs, models.MonitorValidationError{Field: field, Message: message})
+	}
+
+	metadata, _ := monitor["metadata"].(map[string]interface{})
+	if name, _ := metadata["name"].(string); name == "" {
+		fail("metadata.name", "name is required")
+	}
+
+	spec, endpoints, err := parseMonitorSpec(kind, monitor)
+	if err != nil {
+		fail("spec", fmt.Sprintf("invalid spec: %v", err))
+		return errs
+	}
+
+	if spec.Selector == nil {
+		fail("spec.selector", "selector is required")
+	} else if _, err := metav1.LabelSelectorAsSelector(spec.Selector); err != nil {
+		fail("spec.selector", err.Error())
+	}
+	if spec.NamespaceSelector.Any && len(spec.NamespaceSelector.MatchNames) > 0 {
+		fail("spec.namespaceSelector", "any and matchNames are mutually exclusive")
+	}
+
+	if len(endpoints) == 0 {
+		fail("spec."+kind.EndpointsField, "at least one endpoint is required")
+	}
+	for i, e := range endpoints {
+		field := fmt.Sprintf("spec.%s[%d]", kind.EndpointsField, i)
+
+		if e.name() == "" {
+			fail(field+".port", "port or targetPort is required")
+		}
+		if scheme := strings.ToLower(e.Scheme); scheme != "" && scheme != "http" && scheme != "https" {
+			fail(field+".scheme", fmt.Sprintf("unsupported scheme %q", e.Scheme))
+		}
+		if e.Path != "" && !strings.HasPrefix(e.Path, "/") {
+			fail(field+".path", "path must start with /")
+		}
+
+		var interval, timeout model.Duration
+		if e.Interval != "" {
+			if interval, err = model.ParseDuration(e.Interval); err != nil {
+				fail(field+".interval", err.Error())
+			}
+		}
+		if e.ScrapeTimeout != "" {
+			if timeout, err = model.ParseDuration(e.ScrapeTimeout); err != nil {
+				fail(field+".scrapeTimeout", err.Error())
+			}
+		}
+		if interval > 0 && timeout > interval {
+			fail(field+".scrapeTimeout", fmt.Sprintf("scrapeTimeout %s is longer than interval %s", e.ScrapeTimeout, e.Interval))
+		}
+
+		for _, relabelings := range []struct {
+			name    string
+			configs []relabelConfig
+		}{{"relabelings", e.Relabelings}, {"metricRelabelings", e.MetricRelabelings}} {
+			for j, c := range relabelings.configs {
+				relabelField := fmt.Sprintf("%s.%s[%d]", field, relabelings.name, j)
+				if c.Action != "" && !isRelabelAction(c.Action) {
+					fail(relabelField+".action", fmt.Sprintf("unknown relabel action %q", c.Action))
+				}
+				if c.Regex != "" {
+					if _, err := regexp.Compile("^(?:" + c.Regex + ")$"); err != nil {
+						fail(relabelField+".regex", err.Error())
+					}
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func isRelabelAction(action string) bool {
+	for _, a := range relabelActions {
+		if strings.EqualFold(a, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMonitor reports whether the selector of a monitor matches any Service or Pod exposing its endpoint ports,
+// and whether Prometheus has active targets for it
+func CheckMonitor(c *Cluster, kind monitorKind, namespace, name string) (*models.MonitorCheck, error) {
+	monitor, err := c.dynamicClient.Resource(kind.GVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	spec, endpoints, err := parseMonitorSpec(kind, monitor.Object)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s spec: %v", kind.Kind, err)
+	}
+	if spec.Selector == nil {
+		spec.Selector = &metav1.LabelSelector{}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s selector: %v", kind.Kind, err)
+	}
+
+	check := &models.MonitorCheck{
+		Kind:      kind.Kind,
+		Namespace: namespace,
+		Name:      name,
+		Selector:  selector.String(),
+		Matches:   []models.MonitorMatch{},
+		Targets:   []models.MonitorTargetStatus{},
+		Problems:  []string{},
+	}
+
+	// Without a namespace selector the operator only looks in the namespace of the monitor
+	namespaces := []string{namespace}
+	switch {
+	case spec.NamespaceSelector.Any:
+		namespaces = []string{metav1.NamespaceAll}
+		check.Namespaces = []string{"*"}
+	case len(spec.NamespaceSelector.MatchNames) > 0:
+		namespaces = spec.NamespaceSelector.MatchNames
+		check.Namespaces = namespaces
+	default:
+		check.Namespaces = namespaces
+	}
+
+	for _, ns := range namespaces {
+		matches, err := matchMonitorTargets(c, kind, ns, selector.String(), endpoints)
+		if err != nil {
+			return nil, err
+		}
+		check.Matches = append(check.Matches, matches...)
+	}
+
+	if len(check.Matches) == 0 {
+		check.Problems = append(check.Problems, fmt.Sprintf("selector %q matches no %ss in namespaces %s",
+			check.Selector, kind.Target, strings.Join(check.Namespaces, ", ")))
+	} else {
+		for _, e := range endpoints {
+			exposed := false
+			for _, m := range check.Matches {
+				if !slices.Contains(m.MissingPorts, e.name()) {
+					exposed = true
+					break
+				}
+			}
+			if !exposed {
+				check.Problems = append(check.Problems, fmt.Sprintf("no selected %s exposes endpoint port %q", kind.Target, e.name()))
+			}
+		}
+	}
+
+	targets, err := utils.FetchPrometheusTargets()
+	if err != nil {
+		check.PrometheusError = err.Error()
+		check.Problems = append(check.Problems, "Prometheus targets could not be fetched")
+	} else {
+		prefix := fmt.Sprintf("%s/%s/%s/", kind.ScrapePool, namespace, name)
+		for _, t := range targets {
+			if !strings.HasPrefix(t.ScrapePool, prefix) {
+				continue
+			}
+			check.ActiveTargets++
+			if t.Health == "up" {
+				check.HealthyTargets++
+			}
+			check.Targets = append(check.Targets, models.MonitorTargetStatus{
+				ScrapePool: t.ScrapePool,
+				ScrapeURL:  t.ScrapeURL,
+				Health:     t.Health,
+				LastError:  t.LastError,
+				LastScrape: t.LastScrape,
+			})
+		}
+
+		switch {
+		case check.ActiveTargets == 0:
+			check.Problems = append(check.Problems, fmt.Sprintf(
+				"Prometheus has no active targets for this %s, check that the %sSelector and %sNamespaceSelector of the Prometheus resource select it",
+				kind.Kind, kind.ScrapePool, kind.ScrapePool))
+		case check.HealthyTargets < check.ActiveTargets:
+			check.Problems = append(check.Problems, fmt.Sprintf("%d of %d targets are down",
+				check.ActiveTargets-check.HealthyTargets, check.ActiveTargets))
+		}
+	}
+
+	check.Healthy = len(check.Problems) == 0
+	return check, nil
+}
+
+// matchMonitorTargets lists the Services or Pods of a namespace picked by the selector together with their ports
+func matchMonitorTargets(c *Cluster, kind monitorKind, namespace, selector string, endpoints []monitorEndpoint) ([]models.MonitorMatch, error) {
+	opts := metav1.ListOptions{LabelSelector: selector}
+	var matches []models.MonitorMatch
+
+	if kind.Target == "Service" {
+		services, err := c.clientset.CoreV1().Services(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range services.Items {
+			match := models.MonitorMatch{Namespace: service.Namespace, Name: service.Name, Ports: []string{}}
+			for _, p := range service.Spec.Ports {
+				match.Ports = append(match.Ports, portLabel(p.Name, p.Port))
+			}
+			// A targetPort names or numbers a container port of the pods behind the Service
+			var pods []corev1.Pod
+			if len(service.Spec.Selector) > 0 && slices.ContainsFunc(endpoints, func(e monitorEndpoint) bool { return e.Port == "" && e.TargetPort != nil }) {
+				selected, err := c.clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
+					LabelSelector: labels.SelectorFromSet(service.Spec.Selector).String(),
+				})
+				if err != nil {
+					return nil, err
+				}
+				pods = selected.Items
+			}
+			for _, e := range endpoints {
+				found := false
+				switch {
+				case e.Port != "":
+					found = slices.ContainsFunc(service.Spec.Ports, func(p corev1.ServicePort) bool { return e.Port == p.Name })
+				case e.TargetPort != nil && len(pods) > 0:
+					found = slices.ContainsFunc(pods, func(pod corev1.Pod) bool { return podExposesPort(pod, *e.TargetPort) })
+				case e.TargetPort != nil:
+					// Without pods to resolve against, fall back to the target ports declared by the Service
+					found = slices.ContainsFunc(service.Spec.Ports, func(p corev1.ServicePort) bool {
+						target := p.TargetPort
+						if target.Type == intstr.Int && target.IntVal == 0 {
+							target = intstr.FromInt32(p.Port)
+						}
+						return target.String() == e.TargetPort.String()
+					})
+				}
+				if !found {
+					match.MissingPorts = append(match.MissingPorts, e.name())
+				}
+			}
+			matches = append(matches, match)
+		}
+		sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+		return matches, nil
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		match := models.MonitorMatch{Namespace: pod.Namespace, Name: pod.Name, Ports: []string{}}
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				match.Ports = append(match.Ports, portLabel(p.Name, p.ContainerPort))
+			}
+		}
+		for _, e := range endpoints {
+			found := false
+			switch {
+			case e.Port != "":
+				found = podExposesPort(pod, intstr.FromString(e.Port))
+			case e.PortNumber != nil:
+				found = podExposesPort(pod, intstr.FromInt32(*e.PortNumber))
+			case e.TargetPort != nil:
+				found = podExposesPort(pod, *e.TargetPort)
+			}
+			if !found {
+				match.MissingPorts = append(match.MissingPorts, e.name())
+			}
+		}
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	return matches, nil
+}
+
+// podExposesPort reports whether a container of the pod declares the port, by name or by number
+func podExposesPort(pod corev1.Pod, port intstr.IntOrString) bool {
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if (port.Type == intstr.String && port.StrVal == p.Name) || (port.Type == intstr.Int && port.IntVal == p.ContainerPort) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func portLabel(name string, number int32) string {
+	if name == "" {
+		return strconv.Itoa(int(number))
+	}
+	return fmt.Sprintf("%s:%d", name, number)
+}
+
+// ListMonitors returns the monitors of a namespace, or of all namespaces when it is empty
+func ListMonitors(c *Cluster, kind monitorKind, namespace string) ([]map[string]interface{}, error) {
+	list, err := c.dynamicClient.Resource(kind.GVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	monitors := make([]map[string]interface{}, 0, len(list.Items))
+	for _, item := range list.Items {
+		monitors = append(monitors, item.Object)
+	}
+	return monitors, nil
+}
@@ -0,0 +1,144 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestValidateMonitor(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		// spec is the JSON of the monitor spec, decoded like a request body
+		spec string
+		// want lists the failing fields, sorted
+		want []string
+	}{
+		{
+			name:     "service monitor with a named port",
+			resource: "servicemonitors",
+			spec:     `{"selector": {"matchLabels": {"app": "api"}}, "endpoints": [{"port": "metrics", "interval": "30s", "scrapeTimeout": "10s"}]}`,
+		},
+		{
+			name:     "service monitor with a named targetPort",
+			resource: "servicemonitors",
+			spec:     `{"selector": {}, "endpoints": [{"targetPort": "http-metrics"}]}`,
+		},
+		{
+			name:     "service monitor with a numeric targetPort",
+			resource: "servicemonitors",
+			spec:     `{"selector": {}, "endpoints": [{"targetPort": 9090, "scheme": "HTTPS", "path": "/metrics"}]}`,
+		},
+		{
+			name:     "pod monitor with a port number",
+			resource: "podmonitors",
+			spec:     `{"selector": {"matchLabels": {"app": "api"}}, "podMetricsEndpoints": [{"portNumber": 8080}]}`,
+		},
+		{
+			name:     "pod monitor reads podMetricsEndpoints",
+			resource: "podmonitors",
+			spec:     `{"selector": {}, "endpoints": [{"port": "metrics"}]}`,
+			want:     []string{"spec.podMetricsEndpoints"},
+		},
+		{
+			name:     "missing selector and endpoints",
+			resource: "servicemonitors",
+			spec:     `{}`,
+			want:     []string{"spec.endpoints", "spec.selector"},
+		},
+		{
+			name:     "invalid selector and namespace selector",
+			resource: "servicemonitors",
+			spec: `{
+				"selector": {"matchExpressions": [{"key": "app", "operator": "Near"}]},
+				"namespaceSelector": {"any": true, "matchNames": ["shop"]},
+				"endpoints": [{"port": "metrics"}]
+			}`,
+			want: []string{"spec.namespaceSelector", "spec.selector"},
+		},
+		{
+			name:     "endpoint problems",
+			resource: "servicemonitors",
+			spec: `{"selector": {}, "endpoints": [
+				{"scheme": "ftp", "path": "metrics"},
+				{"port": "metrics", "interval": "often", "scrapeTimeout": "1m"},
+				{"port": "metrics", "interval": "30s", "scrapeTimeout": "1m"}
+			]}`,
+			want: []string{
+				"spec.endpoints[0].path",
+				"spec.endpoints[0].port",
+				"spec.endpoints[0].scheme",
+				"spec.endpoints[1].interval",
+				"spec.endpoints[2].scrapeTimeout",
+			},
+		},
+		{
+			name:     "relabelings",
+			resource: "servicemonitors",
+			spec: `{"selector": {}, "endpoints": [{
+				"port": "metrics",
+				"relabelings": [{"action": "Replace", "regex": "(.*)"}, {"action": "rename"}],
+				"metricRelabelings": [{"action": "drop", "regex": "go_(.*"}]
+			}]}`,
+			want: []string{"spec.endpoints[0].metricRelabelings[0].regex", "spec.endpoints[0].relabelings[1].action"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, err := lookupMonitorKind(tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var monitor map[string]interface{}
+			object := fmt.Sprintf(`{"metadata": {"name": "api"}, "spec": %s}`, tt.spec)
+			if err := json.Unmarshal([]byte(object), &monitor); err != nil {
+				t.Fatalf("invalid test object: %v", err)
+			}
+
+			var got []string
+			for _, e := range ValidateMonitor(kind, monitor) {
+				got = append(got, e.Field)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateMonitor() failed fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodExposesPort(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+		{Name: "exporter", Ports: []corev1.ContainerPort{{Name: "http-metrics", ContainerPort: 9100}, {ContainerPort: 9200}}},
+	}}}
+
+	tests := []struct {
+		name string
+		port intstr.IntOrString
+		want bool
+	}{
+		{name: "named port of the first container", port: intstr.FromString("http"), want: true},
+		{name: "named port of a sidecar", port: intstr.FromString("http-metrics"), want: true},
+		{name: "unknown name", port: intstr.FromString("metrics"), want: false},
+		{name: "number given as a name", port: intstr.FromString("8080"), want: false},
+		{name: "numeric port", port: intstr.FromInt32(9100), want: true},
+		{name: "numeric port without a name", port: intstr.FromInt32(9200), want: true},
+		{name: "unknown number", port: intstr.FromInt32(9090), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podExposesPort(pod, tt.port); got != tt.want {
+				t.Errorf("podExposesPort(%s) = %v, want %v", tt.port.String(), got, tt.want)
+			}
+		})
+	}
+}
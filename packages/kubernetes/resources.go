@@ -0,0 +1,203 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"main/packages/models"
+	"main/packages/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// resourceKind is an allowlisted kind served by the generic resource endpoints. The version is left to
+// discovery, so each cluster is read in the version it prefers.
+type resourceKind struct {
+	Group      string
+	Resource   string
+	Namespaced bool
+	// MetadataOnly kinds are read through the metadata client, so e.g. the data of ConfigMaps never reaches the agent
+	MetadataOnly bool
+}
+
+var resourceKinds = map[string]resourceKind{
+	"statefulsets":             {Group: "apps", Resource: "statefulsets", Namespaced: true},
+	"daemonsets":               {Group: "apps", Resource: "daemonsets", Namespaced: true},
+	"replicasets":              {Group: "apps", Resource: "replicasets", Namespaced: true},
+	"jobs":                     {Group: "batch", Resource: "jobs", Namespaced: true},
+	"cronjobs":                 {Group: "batch", Resource: "cronjobs", Namespaced: true},
+	"services":                 {Resource: "services", Namespaced: true},
+	"endpoints":                {Resource: "endpoints", Namespaced: true},
+	"endpointslices":           {Group: "discovery.k8s.io", Resource: "endpointslices", Namespaced: true},
+	"ingresses":                {Group: "networking.k8s.io", Resource: "ingresses", Namespaced: true},
+	"persistentvolumeclaims":   {Resource: "persistentvolumeclaims", Namespaced: true},
+	"persistentvolumes":        {Resource: "persistentvolumes"},
+	"configmaps":               {Resource: "configmaps", Namespaced: true, MetadataOnly: true},
+	"horizontalpodautoscalers": {Group: "autoscaling", Resource: "horizontalpodautoscalers", Namespaced: true},
+}
+
+// resolve asks the cluster's discovery for the preferred version of the kind. A kind the cluster does not serve,
+// like endpointslices on an old cluster, is reported as a 404 from the API.
+func (kind resourceKind) resolve(c *Cluster) (schema.GroupVersionResource, error) {
+	gvr, err := c.mapper.ResourceFor(schema.GroupVersionResource{Group: kind.Group, Resource: kind.Resource})
+	if err != nil {
+		return gvr, &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusNotFound,
+			Reason:  metav1.StatusReasonNotFound,
+			Message: fmt.Sprintf("%s are not served by cluster %q: %v", kind.Resource, c.Name, err),
+		}}
+	}
+	return gvr, nil
+}
+
+// metadataOnly prepares an object read through the metadata client. The last-applied-configuration annotation
+// is dropped since it holds the full object, e.g. the data of ConfigMaps created with kubectl apply.
+func metadataOnly(gvk schema.GroupVersionKind, object *metav1.PartialObjectMetadata) *metav1.PartialObjectMetadata {
+	object.APIVersion, object.Kind = gvk.GroupVersion().String(), gvk.Kind
+	object.ManagedFields = nil
+	delete(object.Annotations, corev1.LastAppliedConfigAnnotation)
+	return object
+}
+
+// resourceKindFromRequest resolves the {resource} path segment, writing a 404 for kinds outside the allowlist
+func resourceKindFromRequest(w http.ResponseWriter, r *http.Request) (resourceKind, bool) {
+	kind, ok := resourceKinds[r.PathValue("resource")]
+	if !ok {
+		utils.WriteJSONError(w, fmt.Sprintf("%v: %s", ErrorUnknownResource, r.PathValue("resource")), http.StatusNotFound)
+	}
+	return kind, ok
+}
+
+// GET /resources
+// ResourceKindsGETHandler returns the kinds available through the generic resource endpoints
+func ResourceKindsGETHandler(w http.ResponseWriter, r *http.Request) {
+	response := []models.ResourceKind{}
+	for name, kind := range resourceKinds {
+		response = append(response, models.ResourceKind{
+			Name:         name,
+			Group:        kind.Group,
+			Namespaced:   kind.Namespaced,
+			MetadataOnly: kind.MetadataOnly,
+		})
+	}
+	sort.Slice(response, func(i, j int) bool { return response[i].Name < response[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+// GET /resources/{resource}
+// ResourcesGETHandler lists an allowlisted kind with the same parameters as the other Kubernetes listings
+func ResourcesGETHandler(w http.ResponseWriter, r *http.Request) {
+	kind, ok := resourceKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+	namespace := namespaceParam(r)
+	if !kind.Namespaced && namespace != "" {
+		utils.WriteJSONError(w, fmt.Sprintf("%s are not namespaced, the namespace parameter does not apply", kind.Resource), http.StatusBadRequest)
+		return
+	}
+	opts, ok := listOptionsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	listClusters(w, r, "Failed to get "+kind.Resource, func(c *Cluster) ([]interface{}, metav1.ListInterface, error) {
+		gvr, err := kind.resolve(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		if kind.MetadataOnly {
+			gvk, err := c.mapper.KindFor(gvr)
+			if err != nil {
+				return nil, nil, err
+			}
+			list, err := c.metadataClient.Resource(gvr).Namespace(namespace).List(context.TODO(), opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			items := make([]interface{}, len(list.Items))
+			for i := range list.Items {
+				items[i] = metadataOnly(gvk, &list.Items[i])
+			}
+			return items, list, nil
+		}
+
+		list, err := c.dynamicClient.Resource(gvr).Namespace(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		items := make([]interface{}, len(list.Items))
+		for i, item := range list.Items {
+			items[i] = item.Object
+		}
+		return items, list, nil
+	})
+}
+
+// GET /resources/{resource}/{name}
+// ResourceGETHandler returns a single object of an allowlisted kind, namespaced kinds need the namespace parameter
+func ResourceGETHandler(w http.ResponseWriter, r *http.Request) {
+	kind, ok := resourceKindFromRequest(w, r)
+	if !ok {
+		return
+	}
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	switch {
+	case kind.Namespaced && namespace == "":
+		utils.WriteJSONError(w, "Namespace query parameter is required", http.StatusBadRequest)
+		return
+	case !kind.Namespaced && namespace != "":
+		utils.WriteJSONError(w, fmt.Sprintf("%s are not namespaced, the namespace parameter does not apply", kind.Resource), http.StatusBadRequest)
+		return
+	}
+
+	gvr, err := kind.resolve(c)
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to get %s", kind.Resource), err)
+		return
+	}
+	var object interface{}
+	if kind.MetadataOnly {
+		object, err = getMetadataOnly(c, gvr, namespace, r.PathValue("name"))
+	} else {
+		object, err = c.dynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), r.PathValue("name"), metav1.GetOptions{})
+	}
+	if err != nil {
+		writeKubernetesError(w, fmt.Sprintf("Failed to get %s", kind.Resource), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(object); err != nil {
+		log.Printf("JSON encoding error: %v", err)
+	}
+}
+
+func getMetadataOnly(c *Cluster, gvr schema.GroupVersionResource, namespace, name string) (*metav1.PartialObjectMetadata, error) {
+	gvk, err := c.mapper.KindFor(gvr)
+	if err != nil {
+		return nil, err
+	}
+	object, err := c.metadataClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return metadataOnly(gvk, object), nil
+}
@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"main/packages/models"
+	"main/packages/utils"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// serverSideMetadata are metadata fields managed by the API server, they are noise in snapshots and diffs
+var serverSideMetadata = []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp", "selfLink"}
+
+// stripServerFields returns a copy of the object without status and server-managed metadata
+func stripServerFields(object map[string]interface{}) map[string]interface{} {
+	if object == nil {
+		return nil
+	}
+
+	stripped := (&unstructured.Unstructured{Object: object}).DeepCopy().Object
+	delete(stripped, "status")
+	for _, field := range serverSideMetadata {
+		unstructured.RemoveNestedField(stripped, "metadata", field)
+	}
+	unstructured.RemoveNestedField(stripped, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	if annotations, found, _ := unstructured.NestedMap(stripped, "metadata", "annotations"); found && len(annotations) == 0 {
+		unstructured.RemoveNestedField(stripped, "metadata", "annotations")
+	}
+	return stripped
+}
+
+// getRuleIfExists returns the current PrometheusRule, or nil if it does not exist
+func getRuleIfExists(c *Cluster, namespace, name string) (*unstructured.Unstructured, error) {
+	rule, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// recordRevision stores a snapshot of a PrometheusRule change. The change already happened,
+// so a failure to record it is logged rather than returned to the caller.
+func recordRevision(c *Cluster, namespace, name, action, author string, before, after *unstructured.Unstructured) {
+	if store == nil {
+		return
+	}
+
+	var previous, current map[string]interface{}
+	if before != nil {
+		previous = stripServerFields(before.Object)
+	}
+	if after != nil {
+		current = stripServerFields(after.Object)
+	}
+
+	revision := &models.RuleRevision{
+		Cluster:   c.Name,
+		Namespace: namespace,
+		Name:      name,
+		Action:    action,
+		Author:    author,
+		CreatedAt: time.Now(),
+		Previous:  previous,
+		Current:   current,
+		Diff:      utils.DiffObjects(previous, current),
+	}
+	if err := store.SaveRuleRevision(revision); err != nil {
+		log.Printf("Failed to record revision of PrometheusRule %s/%s in cluster %q: %v", namespace, name, c.Name, err)
+		return
+	}
+	log.Printf("Recorded revision %d of PrometheusRule %s/%s in cluster %q (%s by %s)", revision.Revision, namespace, name, c.Name, action, author)
+}
+
+// GetRuleRevision returns a stored revision of a PrometheusRule
+func GetRuleRevision(c *Cluster, namespace, name string, revision int) (*models.RuleRevision, error) {
+	stored, err := store.GetRuleRevision(c.Name, namespace, name, revision)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("%w: %d", ErrorRevisionNotFound, revision)
+	}
+	return stored, nil
+}
+
+// RollbackRule restores a PrometheusRule to the object recorded after the given revision,
+// recreating it if it was deleted in the meantime. The restored object goes through the same validation as
+// an update; with dryRun set nothing is written and no revision is recorded. It returns the rule before and after.
+func RollbackRule(c *Cluster, namespace, name string, revision int, author string, dryRun []string) (*unstructured.Unstructured, *unstructured.Unstructured, []models.RuleValidationError, error) {
+	stored, err := GetRuleRevision(c, namespace, name, revision)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if stored.Current == nil {
+		return nil, nil, nil, fmt.Errorf("%w: %d", ErrorRevisionDeleted, revision)
+	}
+
+	restored := &unstructured.Unstructured{Object: stripServerFields(stored.Current)}
+	if errs := ValidatePrometheusRule(restored.Object); len(errs) > 0 {
+		return nil, nil, errs, nil
+	}
+
+	before, err := getRuleIfExists(c, namespace, name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	resource := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace)
+	var after *unstructured.Unstructured
+	if before == nil {
+		after, err = resource.Create(context.TODO(), restored, metav1.CreateOptions{DryRun: dryRun})
+	} else {
+		restored.SetResourceVersion(before.GetResourceVersion())
+		after, err = resource.Update(context.TODO(), restored, metav1.UpdateOptions{DryRun: dryRun})
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if dryRun == nil {
+		recordRevision(c, namespace, name, "rollback", author, before, after)
+	}
+	return before, after, nil, nil
+}
@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"main/packages/models"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type ruleEditOp string
+
+const (
+	ruleEditAdd    ruleEditOp = "add"
+	ruleEditUpdate ruleEditOp = "update"
+	ruleEditRemove ruleEditOp = "remove"
+)
+
+// jsonPatchOperation is a single RFC 6902 operation
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PatchAlertingRule adds, updates or removes a single alerting rule inside a PrometheusRule group with a JSON patch.
+// The patch pins metadata.resourceVersion, so the API server answers with a conflict if the object changed since
+// resourceVersion (or since it was read here, when resourceVersion is empty).
+// Validation problems of the resulting object are returned without writing anything.
+// It returns the object before and after the patch; with dryRun set, the patch is only a server-side dry run.
+func PatchAlertingRule(c *Cluster, namespace, name, group, alert, resourceVersion, author string, dryRun []string, op ruleEditOp, rule *models.Rule) (*unstructured.Unstructured, *unstructured.Unstructured, []models.RuleValidationError, error) {
+	current, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if resourceVersion == "" {
+		resourceVersion = current.GetResourceVersion()
+	}
+
+	groups, _, _ := unstructured.NestedSlice(current.Object, "spec", "groups")
+	groupIndex, ruleIndex := -1, -1
+	for i, g := range groups {
+		groupMap, _ := g.(map[string]interface{})
+		if groupMap["name"] != group {
+			continue
+		}
+		groupIndex = i
+		groupRules, _ := groupMap["rules"].([]interface{})
+		for j, r := range groupRules {
+			if ruleMap, _ := r.(map[string]interface{}); ruleMap["alert"] == alert {
+				ruleIndex = j
+				break
+			}
+		}
+		break
+	}
+	if groupIndex < 0 {
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrorRuleGroupNotFound, group)
+	}
+
+	var ruleValue map[string]interface{}
+	if rule != nil {
+		rule.Alert = alert
+		rule.Record = ""
+		data, err := json.Marshal(rule)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := json.Unmarshal(data, &ruleValue); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	rulesPath := fmt.Sprintf("/spec/groups/%d/rules", groupIndex)
+	patch := []jsonPatchOperation{
+		{Op: "replace", Path: "/metadata/resourceVersion", Value: resourceVersion},
+	}
+	switch op {
+	case ruleEditAdd:
+		if ruleIndex >= 0 {
+			return nil, nil, nil, fmt.Errorf("%w: %s", ErrorAlertExists, alert)
+		}
+		patch = append(patch, jsonPatchOperation{Op: "add", Path: rulesPath + "/-", Value: ruleValue})
+	case ruleEditUpdate:
+		if ruleIndex < 0 {
+			return nil, nil, nil, fmt.Errorf("%w: %s", ErrorAlertNotFound, alert)
+		}
+		patch = append(patch, jsonPatchOperation{Op: "replace", Path: fmt.Sprintf("%s/%d", rulesPath, ruleIndex), Value: ruleValue})
+	case ruleEditRemove:
+		if ruleIndex < 0 {
+			return nil, nil, nil, fmt.Errorf("%w: %s", ErrorAlertNotFound, alert)
+		}
+		patch = append(patch, jsonPatchOperation{Op: "remove", Path: fmt.Sprintf("%s/%d", rulesPath, ruleIndex)})
+	}
+
+	// Lint the object as it will look after the patch before sending it
+	if op != ruleEditRemove {
+		groupMap := groups[groupIndex].(map[string]interface{})
+		groupRules, _ := groupMap["rules"].([]interface{})
+		edited := make([]interface{}, 0, len(groupRules)+1)
+		edited = append(edited, groupRules...)
+		if op == ruleEditAdd {
+			edited = append(edited, ruleValue)
+		} else {
+			edited[ruleIndex] = ruleValue
+		}
+
+		preview := current.DeepCopy()
+		previewGroups, _, _ := unstructured.NestedSlice(preview.Object, "spec", "groups")
+		previewGroups[groupIndex].(map[string]interface{})["rules"] = edited
+		if err := unstructured.SetNestedSlice(preview.Object, previewGroups, "spec", "groups"); err != nil {
+			return nil, nil, nil, err
+		}
+		// Only problems of the edited rule block the edit, other rules are not the caller's concern
+		var errs []models.RuleValidationError
+		for _, e := range ValidatePrometheusRule(preview.Object) {
+			if e.Rule == alert {
+				errs = append(errs, e)
+			}
+		}
+		if len(errs) > 0 {
+			return nil, nil, errs, nil
+		}
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	patched, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Patch(
+		context.TODO(),
+		name,
+		types.JSONPatchType,
+		data,
+		metav1.PatchOptions{DryRun: dryRun},
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if dryRun == nil {
+		recordRevision(c, namespace, name, "update", author, current, patched)
+	}
+	return current, patched, nil, nil
+}
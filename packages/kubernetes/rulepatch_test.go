@@ -0,0 +1,164 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"main/packages/models"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// patchTestRule is the PrometheusRule the PatchAlertingRule tests start from
+const patchTestRule = `{
+	"apiVersion": "monitoring.coreos.com/v1",
+	"kind": "PrometheusRule",
+	"metadata": {"name": "api", "namespace": "shop", "resourceVersion": "7"},
+	"spec": {"groups": [
+		{"name": "records", "rules": [{"record": "job:up:sum", "expr": "sum by (job) (up)"}]},
+		{"name": "alerts", "rules": [
+			{"alert": "ApiDown", "expr": "up == 0", "labels": {"severity": "critical"},
+			 "annotations": {"summary": "API is down", "runbook_url": "https://runbooks/api-down"}},
+			{"alert": "ApiSlow", "expr": "latency_seconds > 1", "labels": {"severity": "warning"},
+			 "annotations": {"summary": "API is slow", "runbook_url": "https://runbooks/api-slow"}}
+		]}
+	]}
+}`
+
+func TestPatchAlertingRule(t *testing.T) {
+	validRule := func(expr string) *models.Rule {
+		return &models.Rule{
+			Expr:        expr,
+			Labels:      map[string]string{"severity": "warning"},
+			Annotations: map[string]string{"summary": "API errors", "runbook_url": "https://runbooks/api-errors"},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		group, alert    string
+		resourceVersion string
+		op              ruleEditOp
+		rule            *models.Rule
+		// wantErr is the sentinel error expected, nil for a successful patch
+		wantErr error
+		// wantInvalid lists the fields failing validation, in which case nothing is patched
+		wantInvalid []string
+		// wantOps is the JSON patch sent as op and path pairs
+		wantOps [][2]string
+		// wantAlerts are the alert names of the alerts group after the patch
+		wantAlerts []string
+	}{
+		{
+			name: "add", group: "alerts", alert: "ApiErrors", op: ruleEditAdd, rule: validRule("errors_total > 0"),
+			wantOps: [][2]string{
+				{"replace", "/metadata/resourceVersion"},
+				{"add", "/spec/groups/1/rules/-"},
+			},
+			wantAlerts: []string{"ApiDown", "ApiSlow", "ApiErrors"},
+		},
+		{
+			name: "update pinned to the given resourceVersion", group: "alerts", alert: "ApiSlow", resourceVersion: "7", op: ruleEditUpdate, rule: validRule("latency_seconds > 2"),
+			wantOps: [][2]string{
+				{"replace", "/metadata/resourceVersion"},
+				{"replace", "/spec/groups/1/rules/1"},
+			},
+			wantAlerts: []string{"ApiDown", "ApiSlow"},
+		},
+		{
+			name: "remove", group: "alerts", alert: "ApiDown", op: ruleEditRemove,
+			wantOps: [][2]string{
+				{"replace", "/metadata/resourceVersion"},
+				{"remove", "/spec/groups/1/rules/0"},
+			},
+			wantAlerts: []string{"ApiSlow"},
+		},
+		{
+			name: "missing group", group: "latency", alert: "ApiSlow", op: ruleEditUpdate, rule: validRule("up"),
+			wantErr: ErrorRuleGroupNotFound,
+		},
+		{
+			name: "missing alert on update", group: "alerts", alert: "ApiGone", op: ruleEditUpdate, rule: validRule("up"),
+			wantErr: ErrorAlertNotFound,
+		},
+		{
+			name: "missing alert on remove", group: "alerts", alert: "ApiGone", op: ruleEditRemove,
+			wantErr: ErrorAlertNotFound,
+		},
+		{
+			name: "duplicate alert name", group: "alerts", alert: "ApiDown", op: ruleEditAdd, rule: validRule("up == 0"),
+			wantErr: ErrorAlertExists,
+		},
+		{
+			name: "invalid rule", group: "alerts", alert: "ApiErrors", op: ruleEditAdd, rule: &models.Rule{Expr: "rate(errors_total[5m]"},
+			wantInvalid: []string{"alerts/2/annotations.runbook_url", "alerts/2/annotations.summary", "alerts/2/expr", "alerts/2/labels.severity"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var object map[string]interface{}
+			if err := json.Unmarshal([]byte(patchTestRule), &object); err != nil {
+				t.Fatalf("invalid test object: %v", err)
+			}
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{prometheusRuleGVR: "PrometheusRuleList"},
+				&unstructured.Unstructured{Object: object},
+			)
+			var sent []jsonPatchOperation
+			client.PrependReactor("patch", "prometheusrules", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if err := json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &sent); err != nil {
+					t.Errorf("patch is not a JSON patch: %v", err)
+				}
+				return false, nil, nil
+			})
+			c := &Cluster{Name: "test", dynamicClient: client}
+
+			_, patched, errs, err := PatchAlertingRule(c, "shop", "api", tt.group, tt.alert, tt.resourceVersion, "alice", nil, tt.op, tt.rule)
+			if tt.wantErr != nil || err != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("PatchAlertingRule() error = %v, want %v", err, tt.wantErr)
+				}
+				if sent != nil {
+					t.Errorf("sent patch %v after an error", sent)
+				}
+				return
+			}
+			if got := validationFields(errs); !reflect.DeepEqual(got, tt.wantInvalid) {
+				t.Fatalf("PatchAlertingRule() failed fields = %v, want %v", got, tt.wantInvalid)
+			}
+			if tt.wantInvalid != nil {
+				if sent != nil || patched != nil {
+					t.Errorf("patched an invalid rule: %v", sent)
+				}
+				return
+			}
+
+			var gotOps [][2]string
+			for _, op := range sent {
+				gotOps = append(gotOps, [2]string{op.Op, op.Path})
+			}
+			if !reflect.DeepEqual(gotOps, tt.wantOps) {
+				t.Errorf("patch ops = %v, want %v", gotOps, tt.wantOps)
+			}
+			if len(sent) > 0 && sent[0].Value != "7" {
+				t.Errorf("pinned resourceVersion = %v, want 7", sent[0].Value)
+			}
+
+			groups, _, _ := unstructured.NestedSlice(patched.Object, "spec", "groups")
+			var gotAlerts []string
+			for _, r := range groups[1].(map[string]interface{})["rules"].([]interface{}) {
+				gotAlerts = append(gotAlerts, r.(map[string]interface{})["alert"].(string))
+			}
+			if !reflect.DeepEqual(gotAlerts, tt.wantAlerts) {
+				t.Errorf("alerts after the patch = %v, want %v", gotAlerts, tt.wantAlerts)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package kubernetes
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+// ruleTestStorage keeps the series of a rule test in memory. It is queried by the PromQL engine and appended to by
+// the test loop and the rules, which only ever add samples in time order; committed samples become visible at once.
+type ruleTestStorage struct {
+	mtx    sync.RWMutex
+	series map[string]*ruleTestSeries
+}
+
+type ruleTestSeries struct {
+	labels  labels.Labels
+	samples []chunks.Sample
+}
+
+func newRuleTestStorage() *ruleTestStorage {
+	return &ruleTestStorage{series: map[string]*ruleTestSeries{}}
+}
+
+func (s *ruleTestStorage) Querier(mint, maxt int64) (storage.Querier, error) {
+	return &ruleTestQuerier{storage: s, mint: mint, maxt: maxt}, nil
+}
+
+func (s *ruleTestStorage) Appender(context.Context) storage.Appender {
+	return &ruleTestAppender{storage: s}
+}
+
+// selectSeries returns the series matching all matchers with their samples between mint and maxt, sorted by labels
+func (s *ruleTestStorage) selectSeries(mint, maxt int64, matchers []*labels.Matcher) []*ruleTestSeries {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var selected []*ruleTestSeries
+	for _, series := range s.series {
+		if !matchesAll(series.labels, matchers) {
+			continue
+		}
+		lo := sort.Search(len(series.samples), func(i int) bool { return series.samples[i].T() >= mint })
+		hi := sort.Search(len(series.samples), func(i int) bool { return series.samples[i].T() > maxt })
+		if lo < hi {
+			selected = append(selected, &ruleTestSeries{labels: series.labels, samples: series.samples[lo:hi:hi]})
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return labels.Compare(selected[i].labels, selected[j].labels) < 0 })
+	return selected
+}
+
+func matchesAll(lset labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+type ruleTestQuerier struct {
+	storage    *ruleTestStorage
+	mint, maxt int64
+}
+
+func (q *ruleTestQuerier) Select(_ context.Context, _ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	selected := q.storage.selectSeries(q.mint, q.maxt, matchers)
+	set := &ruleTestSeriesSet{index: -1}
+	for _, series := range selected {
+		set.series = append(set.series, storage.NewListSeries(series.labels, series.samples))
+	}
+	return set
+}
+
+func (q *ruleTestQuerier) LabelValues(_ context.Context, name string, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	seen := map[string]bool{}
+	values := []string{}
+	for _, series := range q.storage.selectSeries(q.mint, q.maxt, matchers) {
+		if value := series.labels.Get(name); value != "" && !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values, nil, nil
+}
+
+func (q *ruleTestQuerier) LabelNames(_ context.Context, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, series := range q.storage.selectSeries(q.mint, q.maxt, matchers) {
+		series.labels.Range(func(l labels.Label) {
+			if !seen[l.Name] {
+				seen[l.Name] = true
+				names = append(names, l.Name)
+			}
+		})
+	}
+	sort.Strings(names)
+	return names, nil, nil
+}
+
+func (q *ruleTestQuerier) Close() error {
+	return nil
+}
+
+type ruleTestSeriesSet struct {
+	series []storage.Series
+	index  int
+}
+
+func (s *ruleTestSeriesSet) Next() bool {
+	s.index++
+	return s.index < len(s.series)
+}
+
+func (s *ruleTestSeriesSet) At() storage.Series                { return s.series[s.index] }
+func (s *ruleTestSeriesSet) Err() error                        { return nil }
+func (s *ruleTestSeriesSet) Warnings() annotations.Annotations { return nil }
+
+// ruleTestAppender buffers samples until Commit. Like the TSDB head it rejects samples older than the newest one
+// of their series, and a different value at the same timestamp.
+type ruleTestAppender struct {
+	storage *ruleTestStorage
+	pending []ruleTestSeries
+}
+
+func (a *ruleTestAppender) add(l labels.Labels, sample ruleTestSample) (storage.SeriesRef, error) {
+	a.pending = append(a.pending, ruleTestSeries{labels: l, samples: []chunks.Sample{sample}})
+	return 0, nil
+}
+
+func (a *ruleTestAppender) Append(_ storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	return a.add(l, ruleTestSample{t: t, f: v})
+}
+
+func (a *ruleTestAppender) AppendHistogram(_ storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	return a.add(l, ruleTestSample{t: t, h: h, fh: fh})
+}
+
+func (a *ruleTestAppender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (a *ruleTestAppender) UpdateMetadata(ref storage.SeriesRef, _ labels.Labels, _ metadata.Metadata) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (a *ruleTestAppender) AppendCTZeroSample(ref storage.SeriesRef, _ labels.Labels, _, _ int64) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (a *ruleTestAppender) Commit() error {
+	a.storage.mtx.Lock()
+	defer a.storage.mtx.Unlock()
+	defer func() { a.pending = nil }()
+
+	for _, p := range a.pending {
+		key := p.labels.String()
+		series, ok := a.storage.series[key]
+		if !ok {
+			series = &ruleTestSeries{labels: p.labels}
+			a.storage.series[key] = series
+		}
+		sample := p.samples[0]
+		if n := len(series.samples); n > 0 {
+			last := series.samples[n-1]
+			switch {
+			case sample.T() < last.T():
+				return storage.ErrOutOfOrderSample
+			case sample.T() == last.T():
+				if !sameValue(last, sample) {
+					return storage.ErrDuplicateSampleForTimestamp
+				}
+				continue
+			}
+		}
+		series.samples = append(series.samples, sample)
+	}
+	return nil
+}
+
+func (a *ruleTestAppender) Rollback() error {
+	a.pending = nil
+	return nil
+}
+
+func sameValue(a, b chunks.Sample) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case chunkenc.ValHistogram:
+		return a.H().Equals(b.H())
+	case chunkenc.ValFloatHistogram:
+		return a.FH().Equals(b.FH())
+	}
+	return a.F() == b.F() || (math.IsNaN(a.F()) && math.IsNaN(b.F()))
+}
+
+// ruleTestSample is a float or histogram sample of a rule test series
+type ruleTestSample struct {
+	t  int64
+	f  float64
+	h  *histogram.Histogram
+	fh *histogram.FloatHistogram
+}
+
+func (s ruleTestSample) T() int64                      { return s.t }
+func (s ruleTestSample) F() float64                    { return s.f }
+func (s ruleTestSample) H() *histogram.Histogram       { return s.h }
+func (s ruleTestSample) FH() *histogram.FloatHistogram { return s.fh }
+
+func (s ruleTestSample) Type() chunkenc.ValueType {
+	switch {
+	case s.h != nil:
+		return chunkenc.ValHistogram
+	case s.fh != nil:
+		return chunkenc.ValFloatHistogram
+	}
+	return chunkenc.ValFloat
+}
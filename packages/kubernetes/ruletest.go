@@ -0,0 +1,442 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/packages/models"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+)
+
+const (
+	// defaultEvaluationInterval matches the promtool default when a test file sets no evaluation_interval
+	defaultEvaluationInterval = time.Minute
+	// maxRuleTestSteps bounds the evaluations of a test group, the largest eval_time divided by the evaluation_interval
+	maxRuleTestSteps = 100000
+	// maxRuleTestSamples bounds the input samples of a request once the values notation is expanded
+	maxRuleTestSamples = 1000000
+	// maxRuleTestBodyBytes bounds the size of a rule test request
+	maxRuleTestBodyBytes = 4 << 20
+)
+
+var (
+	// histogramNotation matches the native histogram values of the series notation, which contain spaces
+	histogramNotation = regexp.MustCompile(`\{\{[^}]*\}\}`)
+	// repetition matches the xN suffix of the series notation, as in 1+2x10 or _x5
+	repetition = regexp.MustCompile(`x(\d+)$`)
+)
+
+// ValidateRuleTestSteps rejects tests whose largest eval_time needs more than maxRuleTestSteps evaluations.
+// Invalid eval_times are left to the test run, which reports them per group.
+func ValidateRuleTestSteps(evalInterval time.Duration, tests []models.RuleTestGroup) error {
+	var maxEvalTime time.Duration
+	for _, tg := range tests {
+		for _, test := range tg.AlertRuleTests {
+			if t, err := parseEvalTime(test.EvalTime); err == nil {
+				maxEvalTime = max(maxEvalTime, t)
+			}
+		}
+		for _, test := range tg.PromqlExprTests {
+			if t, err := parseEvalTime(test.EvalTime); err == nil {
+				maxEvalTime = max(maxEvalTime, t)
+			}
+		}
+	}
+	if maxEvalTime/evalInterval > maxRuleTestSteps {
+		return fmt.Errorf("%w: eval_time %s with evaluation_interval %s needs more than %d evaluations",
+			ErrorRuleTestTooLong, model.Duration(maxEvalTime), model.Duration(evalInterval), maxRuleTestSteps)
+	}
+	return nil
+}
+
+// ValidateRuleTestSamples rejects requests whose input_series expand to more than maxRuleTestSamples samples.
+// The values are counted without expanding them, an upper bound of what the series notation produces.
+func ValidateRuleTestSamples(tests []models.RuleTestGroup) error {
+	total := 0
+	for _, tg := range tests {
+		for _, series := range tg.InputSeries {
+			for _, token := range strings.Fields(histogramNotation.ReplaceAllString(series.Values, "h")) {
+				n := 1
+				if match := repetition.FindStringSubmatch(token); match != nil {
+					count, err := strconv.Atoi(match[1])
+					if err != nil || count >= maxRuleTestSamples {
+						count = maxRuleTestSamples
+					}
+					n += count
+				}
+				if total += n; total > maxRuleTestSamples {
+					return fmt.Errorf("%w: input_series expand to more than %d samples", ErrorRuleTestTooLarge, maxRuleTestSamples)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RunRuleTests evaluates the rule groups of a PrometheusRule against promtool-style test groups in-process
+func RunRuleTests(spec models.PrometheusRuleSpec, evalInterval time.Duration, tests []models.RuleTestGroup) models.RuleTestResponse {
+	response := models.RuleTestResponse{
+		Passed:  true,
+		Results: make([]models.RuleTestGroupResult, 0, len(tests)),
+	}
+
+	for i, tg := range tests {
+		result := models.RuleTestGroupResult{
+			Name:     tg.Name,
+			Failures: []string{},
+		}
+		if result.Name == "" {
+			result.Name = fmt.Sprintf("test-%d", i)
+		}
+
+		for _, err := range runRuleTestGroup(spec, evalInterval, tg) {
+			result.Failures = append(result.Failures, err.Error())
+		}
+		result.Passed = len(result.Failures) == 0
+		response.Passed = response.Passed && result.Passed
+		response.Results = append(response.Results, result)
+	}
+
+	return response
+}
+
+// runRuleTestGroup follows promtool's test loop: rules are evaluated every evalInterval from time zero
+// and firing alerts are compared with the expectations whose eval_time falls into the current step
+func runRuleTestGroup(spec models.PrometheusRuleSpec, evalInterval time.Duration, tg models.RuleTestGroup) []error {
+	interval := evalInterval
+	if tg.Interval != "" {
+		d, err := model.ParseDuration(tg.Interval)
+		if err != nil {
+			return []error{fmt.Errorf("invalid interval: %v", err)}
+		}
+		interval = time.Duration(d)
+	}
+
+	// Parse every eval_time up front, the largest one bounds the evaluation
+	var maxEvalTime time.Duration
+	alertTests := map[time.Duration][]models.AlertRuleTestCase{}
+	for _, test := range tg.AlertRuleTests {
+		if test.Alertname == "" {
+			return []error{fmt.Errorf("alert_rule_test at eval_time %s misses required attribute alertname", test.EvalTime)}
+		}
+		t, err := parseEvalTime(test.EvalTime)
+		if err != nil {
+			return []error{err}
+		}
+		alertTests[t] = append(alertTests[t], test)
+		maxEvalTime = max(maxEvalTime, t)
+	}
+	exprEvalTimes := make([]time.Duration, len(tg.PromqlExprTests))
+	for i, test := range tg.PromqlExprTests {
+		t, err := parseEvalTime(test.EvalTime)
+		if err != nil {
+			return []error{err}
+		}
+		exprEvalTimes[i] = t
+		maxEvalTime = max(maxEvalTime, t)
+	}
+
+	alertEvalTimes := make([]time.Duration, 0, len(alertTests))
+	for t := range alertTests {
+		alertEvalTimes = append(alertEvalTimes, t)
+	}
+	sort.Slice(alertEvalTimes, func(i, j int) bool { return alertEvalTimes[i] < alertEvalTimes[j] })
+
+	suite, err := newRuleTestSuite(interval, evalInterval, tg.InputSeries)
+	if err != nil {
+		return []error{err}
+	}
+	defer suite.Close()
+
+	opts := &rules.ManagerOptions{
+		QueryFunc:  rules.EngineQueryFunc(suite.engine, suite.storage),
+		Appendable: suite.storage,
+		Context:    context.Background(),
+		NotifyFunc: func(ctx context.Context, expr string, alerts ...*rules.Alert) {},
+		Logger:     log.NewNopLogger(),
+	}
+	groups, err := buildRuleGroups(spec, evalInterval, labels.FromMap(tg.ExternalLabels), opts)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	mint := time.Unix(0, 0).UTC()
+	maxt := mint.Add(maxEvalTime)
+	curr := 0
+	for ts := mint; !ts.After(maxt); ts = ts.Add(evalInterval) {
+		if err := suite.appendTill(ts); err != nil {
+			return append(errs, err)
+		}
+		var evalErrs []error
+		for _, g := range groups {
+			g.Eval(context.Background(), ts)
+			for _, r := range g.Rules() {
+				if r.LastError() != nil {
+					evalErrs = append(evalErrs, fmt.Errorf("rule: %s, time: %s, err: %v", r.Name(), ts.Sub(mint), r.LastError()))
+				}
+			}
+		}
+		if len(evalErrs) > 0 {
+			return append(errs, evalErrs...)
+		}
+
+		// Alerts expected at eval_time are compared with the evaluation at ts when ts <= eval_time < ts+evalInterval
+		for curr < len(alertEvalTimes) && ts.Sub(mint) <= alertEvalTimes[curr] && alertEvalTimes[curr] < ts.Add(evalInterval).Sub(mint) {
+			for _, test := range alertTests[alertEvalTimes[curr]] {
+				if err := compareAlerts(test, firingAlerts(groups, test.Alertname)); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			curr++
+		}
+	}
+
+	for i, test := range tg.PromqlExprTests {
+		if err := compareSamples(suite, test, mint.Add(exprEvalTimes[i])); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// buildRuleGroups turns the typed spec into rule groups that can be evaluated on demand
+func buildRuleGroups(spec models.PrometheusRuleSpec, evalInterval time.Duration, externalLabels labels.Labels, opts *rules.ManagerOptions) ([]*rules.Group, error) {
+	groups := make([]*rules.Group, 0, len(spec.Groups))
+	for _, group := range spec.Groups {
+		groupRules := make([]rules.Rule, 0, len(group.Rules))
+		for _, r := range group.Rules {
+			expr, err := parser.ParseExpr(r.Expr)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: rule %s%s: %v", group.Name, r.Alert, r.Record, err)
+			}
+
+			if r.Record != "" {
+				groupRules = append(groupRules, rules.NewRecordingRule(r.Record, expr, labels.FromMap(r.Labels)))
+				continue
+			}
+
+			hold, err := parseOptionalDuration(r.For)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: alert %s: invalid for: %v", group.Name, r.Alert, err)
+			}
+			keepFiringFor, err := parseOptionalDuration(r.KeepFiringFor)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: alert %s: invalid keep_firing_for: %v", group.Name, r.Alert, err)
+			}
+
+			alertingRule := rules.NewAlertingRule(
+				r.Alert, expr, hold, keepFiringFor,
+				labels.FromMap(r.Labels), labels.FromMap(r.Annotations), externalLabels, "",
+				true, log.NewNopLogger(),
+			)
+			// Restored rules write the ALERTS series as soon as they are evaluated
+			alertingRule.SetRestored(true)
+			groupRules = append(groupRules, alertingRule)
+		}
+
+		groups = append(groups, rules.NewGroup(rules.GroupOptions{
+			Name:     group.Name,
+			Interval: evalInterval,
+			Rules:    groupRules,
+			Opts:     opts,
+		}))
+	}
+	return groups, nil
+}
+
+// firingAlerts collects the firing alerts of every alerting rule with the given name, across all groups
+func firingAlerts(groups []*rules.Group, alertname string) []string {
+	got := []string{}
+	for _, g := range groups {
+		for _, r := range g.Rules() {
+			ar, ok := r.(*rules.AlertingRule)
+			if !ok || ar.Name() != alertname {
+				continue
+			}
+			for _, a := range ar.ActiveAlerts() {
+				if a.State == rules.StateFiring {
+					got = append(got, formatAlert(a.Labels, a.Annotations))
+				}
+			}
+		}
+	}
+	sort.Strings(got)
+	return got
+}
+
+func compareAlerts(test models.AlertRuleTestCase, got []string) error {
+	exp := make([]string, 0, len(test.ExpAlerts))
+	for _, a := range test.ExpAlerts {
+		// The alertname label is added by Prometheus during evaluation
+		lbls := map[string]string{labels.AlertName: test.Alertname}
+		for k, v := range a.ExpLabels {
+			lbls[k] = v
+		}
+		exp = append(exp, formatAlert(labels.FromMap(lbls), labels.FromMap(a.ExpAnnotations)))
+	}
+	sort.Strings(exp)
+
+	if strings.Join(exp, "\n") != strings.Join(got, "\n") {
+		return fmt.Errorf("alertname: %s, time: %s, exp: [%s], got: [%s]",
+			test.Alertname, test.EvalTime, strings.Join(exp, ", "), strings.Join(got, ", "))
+	}
+	return nil
+}
+
+func compareSamples(suite *ruleTestSuite, test models.PromqlExprTestCase, ts time.Time) error {
+	got, err := instantQuery(suite, test.Expr, ts)
+	if err != nil {
+		return fmt.Errorf("expr: %q, time: %s, err: %v", test.Expr, test.EvalTime, err)
+	}
+
+	gotSamples := make([]string, 0, len(got))
+	for _, s := range got {
+		gotSamples = append(gotSamples, formatSample(s.Metric, s.F))
+	}
+	expSamples := make([]string, 0, len(test.ExpSamples))
+	for _, s := range test.ExpSamples {
+		lbls, err := parser.ParseMetric(s.Labels)
+		if err != nil {
+			return fmt.Errorf("expr: %q, time: %s, labels %q: %v", test.Expr, test.EvalTime, s.Labels, err)
+		}
+		expSamples = append(expSamples, formatSample(lbls, s.Value))
+	}
+	sort.Strings(gotSamples)
+	sort.Strings(expSamples)
+
+	if strings.Join(expSamples, "\n") != strings.Join(gotSamples, "\n") {
+		return fmt.Errorf("expr: %q, time: %s, exp: [%s], got: [%s]",
+			test.Expr, test.EvalTime, strings.Join(expSamples, ", "), strings.Join(gotSamples, ", "))
+	}
+	return nil
+}
+
+func instantQuery(suite *ruleTestSuite, expr string, ts time.Time) (promql.Vector, error) {
+	q, err := suite.engine.NewInstantQuery(context.Background(), suite.storage, nil, expr, ts)
+	if err != nil {
+		return nil, err
+	}
+	res := q.Exec(context.Background())
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	switch v := res.Value.(type) {
+	case promql.Vector:
+		return v, nil
+	case promql.Scalar:
+		return promql.Vector{promql.Sample{T: v.T, F: v.V, Metric: labels.Labels{}}}, nil
+	default:
+		return nil, errors.New("result is not a vector or scalar")
+	}
+}
+
+// ruleTestSuite keeps the input series of a test group in memory. Like promtool it appends the samples
+// step by step, so the rules only see the samples up to the time they are evaluated at.
+type ruleTestSuite struct {
+	storage *ruleTestStorage
+	engine  *promql.Engine
+	series  map[uint64]labels.Labels
+	pending map[uint64][]promql.Sample
+}
+
+// newRuleTestSuite expands the input series, spaced by interval from time zero, and opens the storage and engine.
+// Subqueries without a step use the evaluation interval, as in Prometheus.
+func newRuleTestSuite(interval, evalInterval time.Duration, input []models.RuleTestSeries) (*ruleTestSuite, error) {
+	suite := &ruleTestSuite{series: map[uint64]labels.Labels{}, pending: map[uint64][]promql.Sample{}}
+	for _, s := range input {
+		metric, values, err := parser.ParseSeriesDesc(s.Series + " " + s.Values)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input_series %s: %v", s.Series, err)
+		}
+
+		samples := make([]promql.Sample, 0, len(values))
+		for i, v := range values {
+			if !v.Omitted {
+				samples = append(samples, promql.Sample{T: (time.Duration(i) * interval).Milliseconds(), F: v.Value, H: v.Histogram})
+			}
+		}
+		suite.series[metric.Hash()] = metric
+		suite.pending[metric.Hash()] = samples
+	}
+
+	suite.storage = newRuleTestStorage()
+	suite.engine = promql.NewEngine(promql.EngineOpts{
+		MaxSamples:               10000,
+		Timeout:                  100 * time.Second,
+		NoStepSubqueryIntervalFn: func(int64) int64 { return evalInterval.Milliseconds() },
+		EnableAtModifier:         true,
+		EnableNegativeOffset:     true,
+		EnableDelayedNameRemoval: true,
+	})
+	return suite, nil
+}
+
+// appendTill appends the pending samples up to and including ts
+func (s *ruleTestSuite) appendTill(ts time.Time) error {
+	tsMilli := ts.Sub(time.Unix(0, 0).UTC()).Milliseconds()
+	app := s.storage.Appender(context.Background())
+	for h, samples := range s.pending {
+		n := 0
+		for ; n < len(samples) && samples[n].T <= tsMilli; n++ {
+			if err := appendSample(app, s.series[h], samples[n]); err != nil {
+				app.Rollback()
+				return err
+			}
+		}
+		s.pending[h] = samples[n:]
+	}
+	return app.Commit()
+}
+
+func appendSample(app storage.Appender, metric labels.Labels, sample promql.Sample) error {
+	if sample.H != nil {
+		_, err := app.AppendHistogram(0, metric, sample.T, nil, sample.H)
+		return err
+	}
+	_, err := app.Append(0, metric, sample.T, sample.F)
+	return err
+}
+
+func (s *ruleTestSuite) Close() {
+	s.engine.Close()
+}
+
+func parseEvalTime(s string) (time.Duration, error) {
+	d, err := parseOptionalDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid eval_time %q: %v", s, err)
+	}
+	return d, nil
+}
+
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := model.ParseDuration(s)
+	return time.Duration(d), err
+}
+
+func formatAlert(lbls, annotations labels.Labels) string {
+	return fmt.Sprintf("labels:%s annotations:%s", lbls.String(), annotations.String())
+}
+
+func formatSample(lbls labels.Labels, value float64) string {
+	return fmt.Sprintf("%s %v", lbls.String(), value)
+}
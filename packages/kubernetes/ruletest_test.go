@@ -0,0 +1,214 @@
+package kubernetes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"main/packages/models"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ruleTestFixture is the unit test example of the Prometheus documentation, which passes with promtool test rules
+const ruleTestFixture = `
+groups:
+- name: example
+  rules:
+  - alert: InstanceDown
+    expr: up == 0
+    for: 5m
+    labels:
+      severity: page
+    annotations:
+      summary: "Instance {{ $labels.instance }} down"
+      description: "{{ $labels.instance }} of job {{ $labels.job }} has been down for more than 5 minutes."
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`
+
+const ruleTestInput = `
+interval: 1m
+input_series:
+- series: 'up{job="prometheus", instance="localhost:9090"}'
+  values: '0 0 0 0 0 0 0 0 0 0 0 0 0 0 0'
+- series: 'up{job="node_exporter", instance="localhost:9100"}'
+  values: '1+0x6 0 0 0 0 0 0 0 0'
+- series: 'go_goroutines{job="prometheus", instance="localhost:9090"}'
+  values: '10+10x2 30+20x5'
+`
+
+func TestRunRuleTests(t *testing.T) {
+	var spec models.PrometheusRuleSpec
+	if err := yaml.Unmarshal([]byte(ruleTestFixture), &spec); err != nil {
+		t.Fatalf("invalid rule fixture: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// tests are appended to ruleTestInput
+		tests string
+		// failures are substrings of the expected failures, in order
+		failures []string
+	}{
+		{
+			name: "alert fires after its for duration",
+			tests: `
+alert_rule_test:
+- eval_time: 10m
+  alertname: InstanceDown
+  exp_alerts:
+  - exp_labels:
+      severity: page
+      instance: localhost:9090
+      job: prometheus
+    exp_annotations:
+      summary: "Instance localhost:9090 down"
+      description: "localhost:9090 of job prometheus has been down for more than 5 minutes."
+`,
+		},
+		{
+			name: "pending alert is not firing",
+			tests: `
+alert_rule_test:
+- eval_time: 4m
+  alertname: InstanceDown
+`,
+		},
+		{
+			name: "pending alert expected to fire",
+			tests: `
+alert_rule_test:
+- eval_time: 4m
+  alertname: InstanceDown
+  exp_alerts:
+  - exp_labels:
+      severity: page
+      instance: localhost:9090
+      job: prometheus
+`,
+			failures: []string{`alertname: InstanceDown, time: 4m, exp: [labels:{alertname="InstanceDown", instance="localhost:9090", job="prometheus", severity="page"} annotations:{}], got: []`},
+		},
+		{
+			name: "expression samples",
+			tests: `
+promql_expr_test:
+- expr: go_goroutines > 5
+  eval_time: 4m
+  exp_samples:
+  - labels: 'go_goroutines{job="prometheus",instance="localhost:9090"}'
+    value: 50
+- expr: job:up:sum
+  eval_time: 8m
+  exp_samples:
+  - labels: 'job:up:sum{job="prometheus"}'
+    value: 0
+  - labels: 'job:up:sum{job="node_exporter"}'
+    value: 0
+`,
+		},
+		{
+			name: "wrong expression sample",
+			tests: `
+promql_expr_test:
+- expr: go_goroutines > 5
+  eval_time: 4m
+  exp_samples:
+  - labels: 'go_goroutines{job="prometheus",instance="localhost:9090"}'
+    value: 30
+`,
+			failures: []string{`expr: "go_goroutines > 5", time: 4m, exp: [{__name__="go_goroutines", instance="localhost:9090", job="prometheus"} 30], got: [{__name__="go_goroutines", instance="localhost:9090", job="prometheus"} 50]`},
+		},
+		{
+			name: "invalid eval_time",
+			tests: `
+promql_expr_test:
+- expr: up
+  eval_time: soon
+`,
+			failures: []string{`invalid eval_time "soon"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var group models.RuleTestGroup
+			if err := yaml.Unmarshal([]byte(ruleTestInput+tt.tests), &group); err != nil {
+				t.Fatalf("invalid test fixture: %v", err)
+			}
+
+			response := RunRuleTests(spec, time.Minute, []models.RuleTestGroup{group})
+			if len(response.Results) != 1 {
+				t.Fatalf("got %d results, want 1", len(response.Results))
+			}
+			result := response.Results[0]
+			if response.Passed != (len(tt.failures) == 0) || result.Passed != response.Passed {
+				t.Errorf("passed = %v, group passed = %v, want %v", response.Passed, result.Passed, len(tt.failures) == 0)
+			}
+			if len(result.Failures) != len(tt.failures) {
+				t.Fatalf("failures = %q, want %d", result.Failures, len(tt.failures))
+			}
+			for i, want := range tt.failures {
+				if !strings.Contains(result.Failures[i], want) {
+					t.Errorf("failure %d = %q, want it to contain %q", i, result.Failures[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRuleTestSteps(t *testing.T) {
+	tests := []struct {
+		name         string
+		evalInterval time.Duration
+		evalTime     string
+		wantErr      bool
+	}{
+		{name: "within the limit", evalInterval: time.Minute, evalTime: "10m"},
+		{name: "at the limit", evalInterval: time.Minute, evalTime: "100000m"},
+		{name: "over the limit", evalInterval: time.Minute, evalTime: "100001m", wantErr: true},
+		{name: "over the limit with a short interval", evalInterval: time.Second, evalTime: "2d", wantErr: true},
+		{name: "invalid eval_time is left to the run", evalInterval: time.Minute, evalTime: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := []models.RuleTestGroup{{
+				PromqlExprTests: []models.PromqlExprTestCase{{Expr: "up", EvalTime: tt.evalTime}},
+			}}
+			err := ValidateRuleTestSteps(tt.evalInterval, groups)
+			if got := errors.Is(err, ErrorRuleTestTooLong); got != tt.wantErr {
+				t.Errorf("ValidateRuleTestSteps() error = %v, want ErrorRuleTestTooLong %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRuleTestSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		wantErr bool
+	}{
+		{name: "expanding notation", values: []string{"0+10x5 _ 100 _x3 stale"}},
+		{name: "histograms", values: []string{"{{schema:0 sum:5 count:4 buckets:[1 2 1]}}x10"}},
+		{name: "at the limit", values: []string{"1x999999"}},
+		{name: "over the limit", values: []string{"1x1000000"}, wantErr: true},
+		{name: "over the limit across series", values: []string{"1x600000", "1x600000"}, wantErr: true},
+		{name: "huge repetition", values: []string{"1x100000000000000000000"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var group models.RuleTestGroup
+			for _, values := range tt.values {
+				group.InputSeries = append(group.InputSeries, models.RuleTestSeries{Series: "up", Values: values})
+			}
+			err := ValidateRuleTestSamples([]models.RuleTestGroup{group})
+			if got := errors.Is(err, ErrorRuleTestTooLarge); got != tt.wantErr {
+				t.Errorf("ValidateRuleTestSamples() error = %v, want ErrorRuleTestTooLarge %v", err, tt.wantErr)
+			}
+		})
+	}
+}
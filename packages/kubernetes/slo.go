@@ -0,0 +1,408 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/packages/models"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// sloLabel marks the PrometheusRules generated for an SLO, its value is the SLO name
+	sloLabel = "slo"
+	// sloAnnotation keeps the SLO definition on the generated PrometheusRule, which is its only storage
+	sloAnnotation = "slo-definition"
+
+	defaultSLOWindow      = "30d"
+	defaultPageSeverity   = "critical"
+	defaultTicketSeverity = "warning"
+)
+
+// burnRateAlert is one long/short window pair of the multi-window multi-burn-rate alerts from the
+// Google SRE workbook. It fires when budgetSpent of the error budget is consumed within the long window.
+type burnRateAlert struct {
+	long, short time.Duration
+	budgetSpent float64
+	page        bool
+}
+
+var burnRateAlerts = []burnRateAlert{
+	{long: time.Hour, short: 5 * time.Minute, budgetSpent: 0.02, page: true},
+	{long: 6 * time.Hour, short: 30 * time.Minute, budgetSpent: 0.05, page: true},
+	{long: 24 * time.Hour, short: 2 * time.Hour, budgetSpent: 0.10},
+	{long: 72 * time.Hour, short: 6 * time.Hour, budgetSpent: 0.10},
+}
+
+// sloRuleName is the name of the PrometheusRule generated for an SLO
+func sloRuleName(name string) string {
+	return "slo-" + name
+}
+
+// ValidateSLO checks an SLO definition and fills in its defaults
+func ValidateSLO(slo *models.SLO) error {
+	var problems []string
+	if slo.Window == "" {
+		slo.Window = defaultSLOWindow
+	}
+	if slo.PageSeverity == "" {
+		slo.PageSeverity = defaultPageSeverity
+	}
+	if slo.TicketSeverity == "" {
+		slo.TicketSeverity = defaultTicketSeverity
+	}
+
+	if slo.Name == "" {
+		problems = append(problems, "name is required")
+	} else {
+		for _, msg := range validation.IsDNS1123Label(slo.Name) {
+			problems = append(problems, "name: "+msg)
+		}
+	}
+	if slo.Namespace == "" {
+		problems = append(problems, "namespace is required")
+	}
+	if slo.Service == "" {
+		problems = append(problems, "service is required")
+	}
+	if slo.RunbookURL == "" {
+		problems = append(problems, "runbookUrl is required")
+	}
+	if slo.Objective <= 0 || slo.Objective >= 100 {
+		problems = append(problems, "objective must be a percentage between 0 and 100, exclusive")
+	}
+
+	// The compliance window has to cover the longest alert window for the burn rates to make sense
+	longest := burnRateAlerts[len(burnRateAlerts)-1].long
+	if window, err := model.ParseDuration(slo.Window); err != nil {
+		problems = append(problems, fmt.Sprintf("window: %v", err))
+	} else if time.Duration(window) <= longest {
+		problems = append(problems, fmt.Sprintf("window must be longer than %s", model.Duration(longest)))
+	}
+
+	for _, sli := range [][2]string{{"sli.good", slo.SLI.Good}, {"sli.total", slo.SLI.Total}} {
+		field, selector := sli[0], sli[1]
+		if selector == "" {
+			problems = append(problems, field+" is required")
+			continue
+		}
+		expr, err := parser.ParseExpr(selector)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field, err))
+			continue
+		}
+		if _, ok := expr.(*parser.VectorSelector); !ok {
+			problems = append(problems, field+" must be a counter selector such as http_requests_total{job=\"api\"}")
+		}
+	}
+
+	for name := range slo.Labels {
+		if !model.LabelName(name).IsValid() {
+			problems = append(problems, fmt.Sprintf("invalid label name %q", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrorInvalidSLO, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// GenerateSLORule builds the PrometheusRule holding the recording and burn-rate alerting rules of a validated SLO
+func GenerateSLORule(slo models.SLO) (map[string]interface{}, error) {
+	sloWindow, err := model.ParseDuration(slo.Window)
+	if err != nil {
+		return nil, err
+	}
+	budget := (100 - slo.Objective) / 100
+	selector := fmt.Sprintf("{%s=%q, service=%q}", sloLabel, slo.Name, slo.Service)
+	seriesLabels := map[string]string{sloLabel: slo.Name, "service": slo.Service}
+
+	// Error ratios are recorded once for every window used by an alert
+	var windows []time.Duration
+	seen := map[time.Duration]bool{}
+	for _, a := range burnRateAlerts {
+		for _, w := range []time.Duration{a.long, a.short} {
+			if !seen[w] {
+				seen[w] = true
+				windows = append(windows, w)
+			}
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+
+	recording := models.RuleGroup{Name: sloRuleName(slo.Name) + "-recording"}
+	for _, w := range windows {
+		recording.Rules = append(recording.Rules, models.Rule{
+			Record: errorRatioRecord(w),
+			Expr:   fmt.Sprintf("1 - (sum(rate(%s[%s])) / sum(rate(%s[%s])))", slo.SLI.Good, model.Duration(w), slo.SLI.Total, model.Duration(w)),
+			Labels: seriesLabels,
+		})
+	}
+	// The error ratio over the whole compliance window is averaged from the shortest window
+	// instead of computing rates over weeks of raw samples
+	recording.Rules = append(recording.Rules,
+		models.Rule{
+			Record: errorRatioRecord(time.Duration(sloWindow)),
+			Expr:   fmt.Sprintf("avg_over_time(%s%s[%s])", errorRatioRecord(windows[0]), selector, sloWindow),
+			Labels: seriesLabels,
+		},
+		models.Rule{
+			Record: "slo:objective:ratio",
+			Expr:   fmt.Sprintf("vector(%s)", formatRatio(slo.Objective/100)),
+			Labels: seriesLabels,
+		},
+		models.Rule{
+			Record: "slo:error_budget_remaining:ratio",
+			Expr:   fmt.Sprintf("1 - %s%s / %s", errorRatioRecord(time.Duration(sloWindow)), selector, formatRatio(budget)),
+			Labels: seriesLabels,
+		},
+	)
+
+	alerting := models.RuleGroup{Name: sloRuleName(slo.Name) + "-alerts"}
+	for _, page := range []bool{true, false} {
+		var conditions []string
+		for _, a := range burnRateAlerts {
+			if a.page != page {
+				continue
+			}
+			// A burn rate of 1 spends exactly the whole budget over the compliance window
+			threshold := formatRatio(a.budgetSpent * float64(sloWindow) / float64(a.long) * budget)
+			conditions = append(conditions, fmt.Sprintf("(%s%s > %s and %s%s > %s)",
+				errorRatioRecord(a.long), selector, threshold,
+				errorRatioRecord(a.short), selector, threshold,
+			))
+		}
+
+		alert, severity, speed := "SLOSlowBurn", slo.TicketSeverity, "steadily"
+		if page {
+			alert, severity, speed = "SLOFastBurn", slo.PageSeverity, "fast"
+		}
+
+		labels := map[string]string{}
+		for k, v := range slo.Labels {
+			labels[k] = v
+		}
+		labels["severity"] = severity
+		for k, v := range seriesLabels {
+			labels[k] = v
+		}
+
+		alerting.Rules = append(alerting.Rules, models.Rule{
+			Alert:  alert,
+			Expr:   strings.Join(conditions, " or "),
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("%s is burning the error budget of SLO %s %s", slo.Service, slo.Name, speed),
+				"description": fmt.Sprintf("The error ratio is {{ $value | humanizePercentage }}, the %s%% objective over %s allows an error ratio of %s.", formatRatio(slo.Objective), slo.Window, formatRatio(budget)),
+				"runbook_url": slo.RunbookURL,
+			},
+		})
+	}
+
+	data, err := json.Marshal(models.PrometheusRuleSpec{Groups: []models.RuleGroup{recording, alerting}})
+	if err != nil {
+		return nil, err
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+
+	slo.RuleName = ""
+	definition, err := json.Marshal(slo)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"apiVersion": prometheusRuleGVR.GroupVersion().String(),
+		"kind":       "PrometheusRule",
+		"metadata": map[string]interface{}{
+			"name":        sloRuleName(slo.Name),
+			"namespace":   slo.Namespace,
+			"labels":      map[string]interface{}{sloLabel: slo.Name},
+			"annotations": map[string]interface{}{sloAnnotation: string(definition)},
+		},
+		"spec": spec,
+	}, nil
+}
+
+func errorRatioRecord(window time.Duration) string {
+	return "slo:sli_error:ratio_rate" + model.Duration(window).String()
+}
+
+// formatRatio prints a float without the noise of binary floating point, e.g. 0.001 instead of 0.0010000000000000009
+func formatRatio(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1e12)/1e12, 'g', -1, 64)
+}
+
+// sloFromRule reads the SLO definition back from a generated PrometheusRule
+func sloFromRule(rule *unstructured.Unstructured) (*models.SLO, error) {
+	definition, ok := rule.GetAnnotations()[sloAnnotation]
+	if !ok || rule.GetLabels()[sloLabel] == "" {
+		return nil, fmt.Errorf("%w: %s/%s", ErrorNotSLOManaged, rule.GetNamespace(), rule.GetName())
+	}
+
+	var slo models.SLO
+	if err := json.Unmarshal([]byte(definition), &slo); err != nil {
+		return nil, fmt.Errorf("invalid SLO definition on %s/%s: %v", rule.GetNamespace(), rule.GetName(), err)
+	}
+	slo.RuleName = rule.GetName()
+	return &slo, nil
+}
+
+// ListSLOs returns the SLOs of a namespace, or of all namespaces when it is empty
+func ListSLOs(c *Cluster, namespace string) ([]models.SLO, error) {
+	list, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: sloLabel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slos := make([]models.SLO, 0, len(list.Items))
+	for i := range list.Items {
+		slo, err := sloFromRule(&list.Items[i])
+		if err != nil {
+			// Someone else's rule carrying the label is not an SLO
+			continue
+		}
+		slos = append(slos, *slo)
+	}
+	sort.Slice(slos, func(i, j int) bool {
+		if slos[i].Namespace != slos[j].Namespace {
+			return slos[i].Namespace < slos[j].Namespace
+		}
+		return slos[i].Name < slos[j].Name
+	})
+	return slos, nil
+}
+
+// getSLORule returns the PrometheusRule generated for an SLO
+func getSLORule(c *Cluster, namespace, name string) (*unstructured.Unstructured, error) {
+	rule, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Get(context.TODO(), sloRuleName(name), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrorSLONotFound, namespace, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sloFromRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// GetSLO returns a single SLO
+func GetSLO(c *Cluster, namespace, name string) (*models.SLO, error) {
+	rule, err := getSLORule(c, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return sloFromRule(rule)
+}
+
+// CreateSLO generates the PrometheusRule of a new SLO and returns it as created by the API server
+func CreateSLO(c *Cluster, slo models.SLO, author string, dryRun []string) (*unstructured.Unstructured, error) {
+	rule, err := generateValidSLORule(&slo)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(slo.Namespace).Create(
+		context.TODO(),
+		&unstructured.Unstructured{Object: rule},
+		metav1.CreateOptions{DryRun: dryRun},
+	)
+	if apierrors.IsAlreadyExists(err) {
+		// Tell apart an existing SLO from a hand-written rule that happens to use the name
+		if _, getErr := getSLORule(c, slo.Namespace, slo.Name); errors.Is(getErr, ErrorNotSLOManaged) {
+			return nil, getErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dryRun == nil {
+		recordRevision(c, slo.Namespace, created.GetName(), "create", author, nil, created)
+	}
+	return created, nil
+}
+
+// UpdateSLO regenerates the PrometheusRule of an existing SLO, returning it before and after the update
+func UpdateSLO(c *Cluster, slo models.SLO, author string, dryRun []string) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
+	current, err := getSLORule(c, slo.Namespace, slo.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rule, err := generateValidSLORule(&slo)
+	if err != nil {
+		return nil, nil, err
+	}
+	object := &unstructured.Unstructured{Object: rule}
+	object.SetResourceVersion(current.GetResourceVersion())
+
+	updated, err := c.dynamicClient.Resource(prometheusRuleGVR).Namespace(slo.Namespace).Update(
+		context.TODO(),
+		object,
+		metav1.UpdateOptions{DryRun: dryRun},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dryRun == nil {
+		recordRevision(c, slo.Namespace, updated.GetName(), "update", author, current, updated)
+	}
+	return current, updated, nil
+}
+
+// DeleteSLO removes the PrometheusRule of an SLO and returns it as it was before the deletion
+func DeleteSLO(c *Cluster, namespace, name, author string, dryRun []string) (*unstructured.Unstructured, error) {
+	current, err := getSLORule(c, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Delete(context.TODO(), current.GetName(), metav1.DeleteOptions{DryRun: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	if dryRun == nil {
+		recordRevision(c, namespace, current.GetName(), "delete", author, current, nil)
+	}
+	return current, nil
+}
+
+// generateValidSLORule validates the SLO and lints the generated rule like any hand-written one
+func generateValidSLORule(slo *models.SLO) (map[string]interface{}, error) {
+	if err := ValidateSLO(slo); err != nil {
+		return nil, err
+	}
+	rule, err := GenerateSLORule(*slo)
+	if err != nil {
+		return nil, err
+	}
+	if errs := ValidatePrometheusRule(rule); len(errs) > 0 {
+		problems := make([]string, 0, len(errs))
+		for _, e := range errs {
+			problems = append(problems, fmt.Sprintf("%s %s: %s", e.Rule, e.Field, e.Message))
+		}
+		return nil, fmt.Errorf("%w: generated rules are invalid: %s", ErrorInvalidSLO, strings.Join(problems, "; "))
+	}
+	return rule, nil
+}
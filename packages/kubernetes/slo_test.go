@@ -0,0 +1,187 @@
+package kubernetes
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"main/packages/models"
+)
+
+func TestGenerateSLORule(t *testing.T) {
+	// burnRateCondition is one long/short window pair of a burn-rate alert with its expected threshold
+	type burnRateCondition struct {
+		long, short, threshold string
+	}
+
+	tests := []struct {
+		name            string
+		slo             models.SLO
+		budget          string
+		objective       string
+		page, ticket    []burnRateCondition
+		pageSeverity    string
+		ticketSeverity  string
+		extraLabels     map[string]string
+		complianceRatio string
+	}{
+		{
+			name: "99.9% over 30 days",
+			slo: models.SLO{
+				Name: "checkout", Namespace: "shop", Service: "api", Objective: 99.9, Window: "30d",
+				SLI:          models.SLOIndicator{Good: `http_requests_total{code!~"5.."}`, Total: "http_requests_total"},
+				RunbookURL:   "https://runbooks/checkout",
+				PageSeverity: "critical", TicketSeverity: "warning",
+			},
+			budget:    "0.001",
+			objective: "0.999",
+			page: []burnRateCondition{
+				{"1h", "5m", "0.0144"},
+				{"6h", "30m", "0.006"},
+			},
+			ticket: []burnRateCondition{
+				{"1d", "2h", "0.003"},
+				{"3d", "6h", "0.001"},
+			},
+			pageSeverity:    "critical",
+			ticketSeverity:  "warning",
+			complianceRatio: "30d",
+		},
+		{
+			name: "99% over 28 days with custom severities and labels",
+			slo: models.SLO{
+				Name: "search", Namespace: "web", Service: "frontend", Objective: 99, Window: "4w",
+				SLI:          models.SLOIndicator{Good: "search_ok_total", Total: "search_total"},
+				RunbookURL:   "https://runbooks/search",
+				PageSeverity: "page", TicketSeverity: "ticket",
+				Labels: map[string]string{"team": "search"},
+			},
+			budget:    "0.01",
+			objective: "0.99",
+			page: []burnRateCondition{
+				{"1h", "5m", "0.1344"},
+				{"6h", "30m", "0.056"},
+			},
+			ticket: []burnRateCondition{
+				{"1d", "2h", "0.028"},
+				{"3d", "6h", "0.009333333333"},
+			},
+			pageSeverity:    "page",
+			ticketSeverity:  "ticket",
+			extraLabels:     map[string]string{"team": "search"},
+			complianceRatio: "4w",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := GenerateSLORule(tt.slo)
+			if err != nil {
+				t.Fatalf("GenerateSLORule() error = %v", err)
+			}
+			metadata := rule["metadata"].(map[string]interface{})
+			if metadata["name"] != "slo-"+tt.slo.Name || metadata["namespace"] != tt.slo.Namespace {
+				t.Errorf("metadata = %v, want slo-%s in %s", metadata, tt.slo.Name, tt.slo.Namespace)
+			}
+
+			spec, err := ParseRuleSpec(rule)
+			if err != nil {
+				t.Fatalf("ParseRuleSpec() error = %v", err)
+			}
+			if len(spec.Groups) != 2 {
+				t.Fatalf("got %d groups, want a recording and an alerting group", len(spec.Groups))
+			}
+			recording, alerting := spec.Groups[0], spec.Groups[1]
+			selector := fmt.Sprintf(`{slo=%q, service=%q}`, tt.slo.Name, tt.slo.Service)
+
+			records := map[string]string{}
+			var order []string
+			for _, r := range recording.Rules {
+				records[r.Record] = r.Expr
+				order = append(order, r.Record)
+			}
+			wantOrder := []string{
+				"slo:sli_error:ratio_rate5m",
+				"slo:sli_error:ratio_rate30m",
+				"slo:sli_error:ratio_rate1h",
+				"slo:sli_error:ratio_rate2h",
+				"slo:sli_error:ratio_rate6h",
+				"slo:sli_error:ratio_rate1d",
+				"slo:sli_error:ratio_rate3d",
+				"slo:sli_error:ratio_rate" + tt.complianceRatio,
+				"slo:objective:ratio",
+				"slo:error_budget_remaining:ratio",
+			}
+			if !reflect.DeepEqual(order, wantOrder) {
+				t.Errorf("recording rules = %v, want %v", order, wantOrder)
+			}
+
+			wantRatio := fmt.Sprintf("1 - (sum(rate(%s[1h])) / sum(rate(%s[1h])))", tt.slo.SLI.Good, tt.slo.SLI.Total)
+			if got := records["slo:sli_error:ratio_rate1h"]; got != wantRatio {
+				t.Errorf("1h error ratio = %q, want %q", got, wantRatio)
+			}
+			wantCompliance := fmt.Sprintf("avg_over_time(slo:sli_error:ratio_rate5m%s[%s])", selector, tt.complianceRatio)
+			if got := records["slo:sli_error:ratio_rate"+tt.complianceRatio]; got != wantCompliance {
+				t.Errorf("compliance error ratio = %q, want %q", got, wantCompliance)
+			}
+			if got, want := records["slo:objective:ratio"], "vector("+tt.objective+")"; got != want {
+				t.Errorf("objective = %q, want %q", got, want)
+			}
+			wantRemaining := fmt.Sprintf("1 - slo:sli_error:ratio_rate%s%s / %s", tt.complianceRatio, selector, tt.budget)
+			if got := records["slo:error_budget_remaining:ratio"]; got != wantRemaining {
+				t.Errorf("error budget remaining = %q, want %q", got, wantRemaining)
+			}
+
+			if len(alerting.Rules) != 2 {
+				t.Fatalf("got %d alerting rules, want a fast and a slow burn alert", len(alerting.Rules))
+			}
+			for _, want := range []struct {
+				alert      string
+				severity   string
+				conditions []burnRateCondition
+			}{
+				{"SLOFastBurn", tt.pageSeverity, tt.page},
+				{"SLOSlowBurn", tt.ticketSeverity, tt.ticket},
+			} {
+				var got *models.Rule
+				for i := range alerting.Rules {
+					if alerting.Rules[i].Alert == want.alert {
+						got = &alerting.Rules[i]
+					}
+				}
+				if got == nil {
+					t.Errorf("alert %s is missing", want.alert)
+					continue
+				}
+
+				var conditions []string
+				for _, c := range want.conditions {
+					conditions = append(conditions, fmt.Sprintf("(slo:sli_error:ratio_rate%s%s > %s and slo:sli_error:ratio_rate%s%s > %s)",
+						c.long, selector, c.threshold, c.short, selector, c.threshold))
+				}
+				if wantExpr := strings.Join(conditions, " or "); got.Expr != wantExpr {
+					t.Errorf("%s expr = %q, want %q", want.alert, got.Expr, wantExpr)
+				}
+
+				wantLabels := map[string]string{"slo": tt.slo.Name, "service": tt.slo.Service, "severity": want.severity}
+				for k, v := range tt.extraLabels {
+					wantLabels[k] = v
+				}
+				if !reflect.DeepEqual(got.Labels, wantLabels) {
+					t.Errorf("%s labels = %v, want %v", want.alert, got.Labels, wantLabels)
+				}
+				if got.Annotations["runbook_url"] != tt.slo.RunbookURL {
+					t.Errorf("%s runbook_url = %q, want %q", want.alert, got.Annotations["runbook_url"], tt.slo.RunbookURL)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateSLORuleInvalidWindow(t *testing.T) {
+	slo := models.SLO{Name: "checkout", Namespace: "shop", Service: "api", Objective: 99.9, Window: "a month"}
+	if _, err := GenerateSLORule(slo); err == nil {
+		t.Error("GenerateSLORule() accepted an invalid window")
+	}
+}
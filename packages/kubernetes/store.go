@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"log"
+	"main/packages/database"
+	"os"
+)
+
+// store is the Doris database shared with the alertmanager package. It holds the PrometheusRule revisions
+// and the audit log of workload actions, and is where stored alerts are looked up.
+var store *database.DorisClient
+
+// InitStore keeps the Doris client of the Kubernetes handlers, creating the revision and audit log tables.
+// Their rows are keyed by cluster name, so it warns when the default cluster is the generic in-cluster one.
+func InitStore(client *database.DorisClient) error {
+	if defaultCluster == "in-cluster" && os.Getenv("CLUSTER_NAME") == "" {
+		log.Printf("CLUSTER_NAME is not set, rule revisions and workload actions are stored for cluster %q; set it when agents of several clusters share the database", defaultCluster)
+	}
+	if err := client.CreateRevisionsTableIfNotExists(); err != nil {
+		return err
+	}
+	if err := client.CreateWorkloadActionsTableIfNotExists(); err != nil {
+		return err
+	}
+
+	store = client
+	return nil
+}
@@ -0,0 +1,182 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"main/packages/models"
+	"main/packages/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+const (
+	viewFull    = "full"
+	viewSummary = "summary"
+
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
+	legacyNodeRoleLabel = "kubernetes.io/role"
+)
+
+// viewFromRequest returns the view query parameter, full when it is empty, writing a 400 for unknown views
+func viewFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch view := r.URL.Query().Get("view"); view {
+	case "", viewFull:
+		return viewFull, true
+	case viewSummary:
+		return viewSummary, true
+	default:
+		utils.WriteJSONError(w, fmt.Sprintf("Unknown view %q, expected full or summary", view), http.StatusBadRequest)
+		return "", false
+	}
+}
+
+func age(created metav1.Time, now time.Time) string {
+	if created.IsZero() {
+		return ""
+	}
+	return duration.HumanDuration(now.Sub(created.Time))
+}
+
+// SummarizePods projects pods to name, phase, container readiness, restarts, node, age and owner
+func SummarizePods(pods []corev1.Pod) []models.PodSummary {
+	now := time.Now()
+	summaries := make([]models.PodSummary, 0, len(pods))
+	for _, pod := range pods {
+		summary := models.PodSummary{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			Phase:           string(pod.Status.Phase),
+			TotalContainers: len(pod.Spec.Containers),
+			Node:            pod.Spec.NodeName,
+			CreatedAt:       pod.CreationTimestamp.Time,
+			Age:             age(pod.CreationTimestamp, now),
+			Owner:           podOwner(&pod),
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready {
+				summary.ReadyContainers++
+			}
+			summary.Restarts += status.RestartCount
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// podOwner returns the controller of a pod. A ReplicaSet named after the pod-template-hash label
+// belongs to a Deployment, which is reported instead so the dashboard can link to it.
+func podOwner(pod *corev1.Pod) *models.OwnerSummary {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil
+	}
+
+	owner := &models.OwnerSummary{Kind: ref.Kind, Name: ref.Name}
+	if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ref.Kind == "ReplicaSet" && hash != "" {
+		if name, found := strings.CutSuffix(ref.Name, "-"+hash); found {
+			owner.Kind, owner.Name = "Deployment", name
+		}
+	}
+	return owner
+}
+
+// SummarizeDeployments projects deployments to their replica counts and conditions
+func SummarizeDeployments(deployments []appsv1.Deployment) []models.DeploymentSummary {
+	now := time.Now()
+	summaries := make([]models.DeploymentSummary, 0, len(deployments))
+	for _, deployment := range deployments {
+		// A nil replicas field means the default of 1
+		desired := int32(1)
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+
+		conditions := []models.ConditionSummary{}
+		for _, condition := range deployment.Status.Conditions {
+			conditions = append(conditions, models.ConditionSummary{
+				Type:    string(condition.Type),
+				Status:  string(condition.Status),
+				Reason:  condition.Reason,
+				Message: condition.Message,
+			})
+		}
+
+		summaries = append(summaries, models.DeploymentSummary{
+			Name:              deployment.Name,
+			Namespace:         deployment.Namespace,
+			DesiredReplicas:   desired,
+			ReadyReplicas:     deployment.Status.ReadyReplicas,
+			UpdatedReplicas:   deployment.Status.UpdatedReplicas,
+			AvailableReplicas: deployment.Status.AvailableReplicas,
+			Conditions:        conditions,
+			CreatedAt:         deployment.CreationTimestamp.Time,
+			Age:               age(deployment.CreationTimestamp, now),
+		})
+	}
+	return summaries
+}
+
+// SummarizeNodes projects nodes to their roles, conditions, capacity and kubelet version
+func SummarizeNodes(nodes []corev1.Node) []models.NodeSummary {
+	now := time.Now()
+	summaries := make([]models.NodeSummary, 0, len(nodes))
+	for _, node := range nodes {
+		conditions := []models.ConditionSummary{}
+		for _, condition := range node.Status.Conditions {
+			conditions = append(conditions, models.ConditionSummary{
+				Type:    string(condition.Type),
+				Status:  string(condition.Status),
+				Reason:  condition.Reason,
+				Message: condition.Message,
+			})
+		}
+
+		summaries = append(summaries, models.NodeSummary{
+			Name:           node.Name,
+			Roles:          nodeRoles(node.Labels),
+			Conditions:     conditions,
+			Capacity:       resourceStrings(node.Status.Capacity),
+			Allocatable:    resourceStrings(node.Status.Allocatable),
+			KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+			Unschedulable:  node.Spec.Unschedulable,
+			CreatedAt:      node.CreationTimestamp.Time,
+			Age:            age(node.CreationTimestamp, now),
+		})
+	}
+	return summaries
+}
+
+// nodeRoles reads the roles from the node-role.kubernetes.io/<role> labels and the legacy kubernetes.io/role label
+func nodeRoles(labels map[string]string) []string {
+	set := map[string]bool{}
+	for key, value := range labels {
+		switch {
+		case strings.HasPrefix(key, nodeRoleLabelPrefix) && key != nodeRoleLabelPrefix:
+			set[strings.TrimPrefix(key, nodeRoleLabelPrefix)] = true
+		case key == legacyNodeRoleLabel && value != "":
+			set[value] = true
+		}
+	}
+
+	roles := []string{}
+	for role := range set {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func resourceStrings(resources corev1.ResourceList) map[string]string {
+	out := make(map[string]string, len(resources))
+	for name, quantity := range resources {
+		out[string(name)] = quantity.String()
+	}
+	return out
+}
@@ -0,0 +1,392 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"main/packages/config"
+	"main/packages/models"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// templateAnnotation records on a rendered PrometheusRule which template it came from
+const templateAnnotation = "rule-template"
+
+// builtinRuleTemplates cover the alerts most teams end up writing by hand
+var builtinRuleTemplates = []models.RuleTemplate{
+	{
+		Name:        "pod-crashlooping",
+		Description: "A container keeps restarting and is stuck in CrashLoopBackOff",
+		Parameters: []models.RuleTemplateParameter{
+			{Name: "namespace", Description: "Regular expression of the namespaces to watch", Default: ".*"},
+			{Name: "for", Description: "How long the container has to be crash looping", Default: "15m"},
+			{Name: "severity", Default: "warning"},
+			{Name: "runbook_url", Default: "https://runbooks.prometheus-operator.dev/runbooks/kubernetes/kubepodcrashlooping"},
+		},
+		Groups: []models.RuleGroup{{
+			Name: "pod-crashlooping",
+			Rules: []models.Rule{{
+				Alert: "PodCrashLooping",
+				Expr:  `max_over_time(kube_pod_container_status_waiting_reason{reason="CrashLoopBackOff", namespace=~"[[ .namespace ]]"}[5m]) >= 1`,
+				For:   "[[ .for ]]",
+				Labels: map[string]string{
+					"severity": "[[ .severity ]]",
+				},
+				Annotations: map[string]string{
+					"summary":     "Pod {{ $labels.namespace }}/{{ $labels.pod }} ({{ $labels.container }}) is crash looping",
+					"runbook_url": "[[ .runbook_url ]]",
+				},
+			}},
+		}},
+	},
+	{
+		Name:        "high-5xx-rate",
+		Description: "The share of HTTP requests answered with a 5xx status is above a threshold",
+		Parameters: []models.RuleTemplateParameter{
+			{Name: "job", Description: "Job label of the service", Required: true},
+			{Name: "metric", Description: "Request counter", Default: "http_requests_total"},
+			{Name: "status_label", Description: "Label holding the HTTP status code", Default: "code"},
+			{Name: "threshold", Description: "Ratio of failing requests, between 0 and 1", Default: "0.05"},
+			{Name: "for", Default: "10m"},
+			{Name: "severity", Default: "critical"},
+			{Name: "runbook_url", Required: true},
+		},
+		Groups: []models.RuleGroup{{
+			Name: "[[ .job ]]-5xx-rate",
+			Rules: []models.Rule{{
+				Alert: "High5xxRate",
+				Expr:  `sum by (job) (rate([[ .metric ]]{job="[[ .job ]]", [[ .status_label ]]=~"5.."}[5m])) / sum by (job) (rate([[ .metric ]]{job="[[ .job ]]"}[5m])) > [[ .threshold ]]`,
+				For:   "[[ .for ]]",
+				Labels: map[string]string{
+					"severity": "[[ .severity ]]",
+				},
+				Annotations: map[string]string{
+					"summary":     "{{ $labels.job }} answers {{ $value | humanizePercentage }} of requests with a 5xx status",
+					"runbook_url": "[[ .runbook_url ]]",
+				},
+			}},
+		}},
+	},
+	{
+		Name:        "pvc-filling-up",
+		Description: "A PersistentVolumeClaim is running out of free space",
+		Parameters: []models.RuleTemplateParameter{
+			{Name: "namespace", Description: "Regular expression of the namespaces to watch", Default: ".*"},
+			{Name: "threshold", Description: "Ratio of available space below which the alert fires", Default: "0.1"},
+			{Name: "for", Default: "5m"},
+			{Name: "severity", Default: "warning"},
+			{Name: "runbook_url", Default: "https://runbooks.prometheus-operator.dev/runbooks/kubernetes/kubepersistentvolumefillingup"},
+		},
+		Groups: []models.RuleGroup{{
+			Name: "pvc-filling-up",
+			Rules: []models.Rule{{
+				Alert: "PersistentVolumeClaimFillingUp",
+				Expr:  `kubelet_volume_stats_available_bytes{namespace=~"[[ .namespace ]]"} / kubelet_volume_stats_capacity_bytes{namespace=~"[[ .namespace ]]"} < [[ .threshold ]]`,
+				For:   "[[ .for ]]",
+				Labels: map[string]string{
+					"severity": "[[ .severity ]]",
+				},
+				Annotations: map[string]string{
+					"summary":     "PVC {{ $labels.namespace }}/{{ $labels.persistentvolumeclaim }} has {{ $value | humanizePercentage }} free space left",
+					"runbook_url": "[[ .runbook_url ]]",
+				},
+			}},
+		}},
+	},
+	{
+		Name:        "certificate-expiry",
+		Description: "A TLS certificate probed by the blackbox exporter expires soon",
+		Parameters: []models.RuleTemplateParameter{
+			{Name: "job", Description: "Regular expression of the probe jobs to watch", Default: ".*"},
+			{Name: "days", Description: "Days before expiry at which the alert fires", Default: "14"},
+			{Name: "severity", Default: "warning"},
+			{Name: "runbook_url", Required: true},
+		},
+		Groups: []models.RuleGroup{{
+			Name: "certificate-expiry",
+			Rules: []models.Rule{{
+				Alert: "CertificateExpiringSoon",
+				Expr:  `(probe_ssl_earliest_cert_expiry{job=~"[[ .job ]]"} - time()) / 86400 < [[ .days ]]`,
+				Labels: map[string]string{
+					"severity": "[[ .severity ]]",
+				},
+				Annotations: map[string]string{
+					"summary":     "The certificate of {{ $labels.instance }} expires in {{ $value | humanize }} days",
+					"runbook_url": "[[ .runbook_url ]]",
+				},
+			}},
+		}},
+	},
+}
+
+func init() {
+	for i := range builtinRuleTemplates {
+		builtinRuleTemplates[i].Source = "builtin"
+	}
+}
+
+// ListRuleTemplates returns the built-in templates and the user-defined ones from the templates ConfigMap,
+// sorted by name. A user-defined template replaces the built-in template with the same name.
+func ListRuleTemplates() ([]models.RuleTemplate, error) {
+	byName := map[string]models.RuleTemplate{}
+	for _, t := range builtinRuleTemplates {
+		byName[t.Name] = t
+	}
+
+	userTemplates, err := loadUserRuleTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range userTemplates {
+		byName[t.Name] = t
+	}
+
+	templates := make([]models.RuleTemplate, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// loadUserRuleTemplates reads the ConfigMap named by RULE_TEMPLATES_CONFIGMAP in RULE_TEMPLATES_NAMESPACE
+// of the default cluster. Every data key holds one template as YAML, the key without extension is its default name.
+// A missing ConfigMap, or running without Kubernetes, means there are no user-defined templates.
+func loadUserRuleTemplates() ([]models.RuleTemplate, error) {
+	c := DefaultCluster()
+	if c == nil {
+		return nil, nil
+	}
+	name := config.GetEnv("RULE_TEMPLATES_CONFIGMAP", "rule-templates")
+	namespace := config.GetEnv("RULE_TEMPLATES_NAMESPACE", "monitoring")
+
+	configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]models.RuleTemplate, 0, len(configMap.Data))
+	for key, data := range configMap.Data {
+		var t models.RuleTemplate
+		if err := yaml.Unmarshal([]byte(data), &t); err != nil {
+			// A broken entry should not hide the other templates
+			log.Printf("Skipping rule template %q in ConfigMap %s/%s: %v", key, namespace, name, err)
+			continue
+		}
+		if t.Name == "" {
+			t.Name = strings.TrimSuffix(strings.TrimSuffix(key, ".yaml"), ".yml")
+		}
+		t.Source = "configmap"
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// GetRuleTemplate looks up a single template by name
+func GetRuleTemplate(name string) (*models.RuleTemplate, error) {
+	templates, err := ListRuleTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrorTemplateNotFound, name)
+}
+
+// RenderRuleTemplate fills in the parameters of a template and returns the resulting PrometheusRule object
+func RenderRuleTemplate(t *models.RuleTemplate, request models.RuleTemplateRenderRequest) (map[string]interface{}, error) {
+	if request.Namespace == "" {
+		return nil, fmt.Errorf("%w: namespace is required", ErrorInvalidRenderParams)
+	}
+	name := request.Name
+	if name == "" {
+		name = t.Name
+	}
+
+	params, err := resolveTemplateParameters(t, request.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]models.RuleGroup, 0, len(t.Groups))
+	for _, g := range t.Groups {
+		group := models.RuleGroup{Rules: make([]models.Rule, 0, len(g.Rules))}
+		if group.Name, err = renderTemplateString(g.Name, params); err != nil {
+			return nil, err
+		}
+		if group.Interval, err = renderTemplateString(g.Interval, params); err != nil {
+			return nil, err
+		}
+		for _, r := range g.Rules {
+			rule, err := renderTemplateRule(r, params)
+			if err != nil {
+				return nil, err
+			}
+			group.Rules = append(group.Rules, rule)
+		}
+		groups = append(groups, group)
+	}
+
+	// Round-trip the typed spec so the object holds plain JSON values like one decoded from a request
+	data, err := json.Marshal(models.PrometheusRuleSpec{Groups: groups})
+	if err != nil {
+		return nil, err
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"name":        name,
+		"namespace":   request.Namespace,
+		"annotations": map[string]interface{}{templateAnnotation: t.Name},
+	}
+	if len(request.Labels) > 0 {
+		labels := make(map[string]interface{}, len(request.Labels))
+		for k, v := range request.Labels {
+			labels[k] = v
+		}
+		metadata["labels"] = labels
+	}
+
+	return map[string]interface{}{
+		"apiVersion": prometheusRuleGVR.GroupVersion().String(),
+		"kind":       "PrometheusRule",
+		"metadata":   metadata,
+		"spec":       spec,
+	}, nil
+}
+
+// resolveTemplateParameters applies defaults and rejects unknown or missing parameters
+func resolveTemplateParameters(t *models.RuleTemplate, values map[string]string) (map[string]string, error) {
+	params := make(map[string]string, len(t.Parameters))
+	known := make(map[string]bool, len(t.Parameters))
+	var missing []string
+	for _, p := range t.Parameters {
+		known[p.Name] = true
+		value, ok := values[p.Name]
+		if !ok || value == "" {
+			value = p.Default
+		}
+		if value == "" && p.Required {
+			missing = append(missing, p.Name)
+		}
+		params[p.Name] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing required parameters %s", ErrorInvalidRenderParams, strings.Join(missing, ", "))
+	}
+
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: unknown parameters %s", ErrorInvalidRenderParams, strings.Join(unknown, ", "))
+	}
+
+	return params, nil
+}
+
+func renderTemplateRule(r models.Rule, params map[string]string) (models.Rule, error) {
+	var rendered models.Rule
+	var err error
+	for _, field := range []struct{ out, in *string }{
+		{&rendered.Record, &r.Record},
+		{&rendered.Alert, &r.Alert},
+		{&rendered.For, &r.For},
+		{&rendered.KeepFiringFor, &r.KeepFiringFor},
+	} {
+		if *field.out, err = renderTemplateString(*field.in, params); err != nil {
+			return rendered, err
+		}
+	}
+	if rendered.Expr, err = renderTemplateExpr(r.Expr, params); err != nil {
+		return rendered, err
+	}
+	if rendered.Labels, err = renderTemplateMap(r.Labels, params); err != nil {
+		return rendered, err
+	}
+	if rendered.Annotations, err = renderTemplateMap(r.Annotations, params); err != nil {
+		return rendered, err
+	}
+	return rendered, nil
+}
+
+func renderTemplateMap(m map[string]string, params map[string]string) (map[string]string, error) {
+	if m == nil {
+		return nil, nil
+	}
+	rendered := make(map[string]string, len(m))
+	for k, v := range m {
+		key, err := renderTemplateString(k, params)
+		if err != nil {
+			return nil, err
+		}
+		if rendered[key], err = renderTemplateString(v, params); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// renderTemplateExpr renders a rule expression. Parameters it uses may not contain quotes, backslashes or control
+// characters, which could end a string literal or matcher and splice PromQL into the rule, and the result has to parse.
+func renderTemplateExpr(text string, params map[string]string) (string, error) {
+	checked := make(map[string]string, len(params))
+	for name, value := range params {
+		checked[name] = value
+		if strings.ContainsAny(value, "\"'`\\") || strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			// Stand in a marker for the unsafe value, it only matters when the expression references it
+			checked[name] = "\x00" + name + "\x00"
+		}
+	}
+	expr, err := renderTemplateString(text, checked)
+	if err != nil {
+		return "", err
+	}
+	if _, name, ok := strings.Cut(expr, "\x00"); ok {
+		name, _, _ = strings.Cut(name, "\x00")
+		return "", fmt.Errorf("%w: parameter %s may not contain quotes, backslashes or control characters", ErrorInvalidRenderParams, name)
+	}
+	if _, err := parser.ParseExpr(expr); err != nil {
+		return "", fmt.Errorf("%w: rendered expr %q does not parse: %v", ErrorInvalidRenderParams, expr, err)
+	}
+	return expr, nil
+}
+
+// renderTemplateString expands [[ ]] actions, referencing a parameter the template does not declare is an error
+func renderTemplateString(text string, params map[string]string) (string, error) {
+	if !strings.Contains(text, "[[") {
+		return text, nil
+	}
+	tmpl, err := template.New("").Delims("[[", "]]").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrorInvalidTemplate, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, params); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrorInvalidTemplate, err)
+	}
+	return b.String(), nil
+}
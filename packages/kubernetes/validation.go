@@ -0,0 +1,164 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"main/packages/models"
+	"main/packages/utils"
+
+	"github.com/prometheus/common/model"
+)
+
+// requiredAlertAnnotations must be set on every alerting rule so on-call engineers get context and a runbook.
+var requiredAlertAnnotations = []string{"summary", "runbook_url"}
+
+// ParseRuleSpec converts the spec of an unstructured PrometheusRule into typed rule groups
+func ParseRuleSpec(rule map[string]interface{}) (models.PrometheusRuleSpec, error) {
+	var spec models.PrometheusRuleSpec
+
+	rawSpec, ok := rule["spec"]
+	if !ok {
+		return spec, fmt.Errorf("spec is required")
+	}
+
+	data, err := json.Marshal(rawSpec)
+	if err != nil {
+		return spec, err
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return spec, err
+	}
+	return spec, nil
+}
+
+// ValidatePrometheusRule lints a PrometheusRule object and returns every problem found, or nil if it is valid
+func ValidatePrometheusRule(rule map[string]interface{}) []models.RuleValidationError {
+	var errs []models.RuleValidationError
+
+	metadata, _ := rule["metadata"].(map[string]interface{})
+	if name, _ := metadata["name"].(string); name == "" {
+		errs = append(errs, models.RuleValidationError{Index: -1, Field: "metadata.name", Message: "name is required"})
+	}
+
+	spec, err := ParseRuleSpec(rule)
+	if err != nil {
+		return append(errs, models.RuleValidationError{Index: -1, Field: "spec", Message: fmt.Sprintf("invalid spec: %v", err)})
+	}
+
+	if len(spec.Groups) == 0 {
+		errs = append(errs, models.RuleValidationError{Index: -1, Field: "spec.groups", Message: "at least one group is required"})
+	}
+
+	groupNames := map[string]bool{}
+	alertNames := map[string]string{}
+	for _, group := range spec.Groups {
+		if group.Name == "" {
+			errs = append(errs, models.RuleValidationError{Index: -1, Field: "name", Message: "group name is required"})
+		} else if groupNames[group.Name] {
+			errs = append(errs, models.RuleValidationError{Group: group.Name, Index: -1, Field: "name", Message: "duplicate group name"})
+		}
+		groupNames[group.Name] = true
+
+		if group.Interval != "" {
+			if _, err := model.ParseDuration(group.Interval); err != nil {
+				errs = append(errs, models.RuleValidationError{Group: group.Name, Index: -1, Field: "interval", Message: err.Error()})
+			}
+		}
+
+		if len(group.Rules) == 0 {
+			errs = append(errs, models.RuleValidationError{Group: group.Name, Index: -1, Field: "rules", Message: "group has no rules"})
+		}
+
+		for i, r := range group.Rules {
+			for _, e := range validateRule(r) {
+				e.Group = group.Name
+				e.Index = i
+				errs = append(errs, e)
+			}
+
+			if r.Alert != "" {
+				if previous, ok := alertNames[r.Alert]; ok {
+					errs = append(errs, models.RuleValidationError{
+						Group:   group.Name,
+						Rule:    r.Alert,
+						Index:   i,
+						Field:   "alert",
+						Message: fmt.Sprintf("duplicate alert name, already defined in group %q", previous),
+					})
+				} else {
+					alertNames[r.Alert] = group.Name
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateRule checks a single rule, the returned errors have no group or index set
+func validateRule(r models.Rule) []models.RuleValidationError {
+	var errs []models.RuleValidationError
+	name := r.Alert
+	if name == "" {
+		name = r.Record
+	}
+	fail := func(field, message string) {
+		errs = append(errs, models.RuleValidationError{Rule: name, Field: field, Message: message})
+	}
+
+	switch {
+	case r.Alert == "" && r.Record == "":
+		fail("alert", "one of alert or record is required")
+	case r.Alert != "" && r.Record != "":
+		fail("alert", "only one of alert or record may be set")
+	case r.Record != "" && !model.IsValidMetricName(model.LabelValue(r.Record)):
+		fail("record", fmt.Sprintf("invalid recording rule name %q", r.Record))
+	}
+
+	if r.Expr == "" {
+		fail("expr", "expr is required")
+	} else if result := utils.ValidatePromQL(r.Expr); !result.Valid {
+		message := result.Error
+		if result.Position != nil {
+			message = fmt.Sprintf("line %d, column %d: %s", result.Position.Line, result.Position.Column, result.Error)
+		}
+		fail("expr", message)
+	}
+
+	for _, duration := range [][2]string{{"for", r.For}, {"keep_firing_for", r.KeepFiringFor}} {
+		field, value := duration[0], duration[1]
+		if value == "" {
+			continue
+		}
+		if r.Record != "" {
+			fail(field, fmt.Sprintf("%s is not allowed on recording rules", field))
+		} else if _, err := model.ParseDuration(value); err != nil {
+			fail(field, err.Error())
+		}
+	}
+
+	for labelName := range r.Labels {
+		if !model.LabelName(labelName).IsValid() {
+			fail("labels", fmt.Sprintf("invalid label name %q", labelName))
+		}
+	}
+
+	if r.Record != "" {
+		if len(r.Annotations) > 0 {
+			fail("annotations", "annotations are not allowed on recording rules")
+		}
+		return errs
+	}
+
+	if r.Labels["severity"] == "" {
+		fail("labels.severity", "severity label is required")
+	}
+	for _, annotation := range requiredAlertAnnotations {
+		if r.Annotations[annotation] == "" {
+			fail("annotations."+annotation, fmt.Sprintf("%s annotation is required", annotation))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,186 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"main/packages/models"
+)
+
+// validAlert is an alerting rule that passes validation, test cases override single fields of it
+const validAlert = `{
+	"alert": "HighErrorRate",
+	"expr": "rate(errors_total[5m]) > 1",
+	"for": "5m",
+	"labels": {"severity": "critical"},
+	"annotations": {"summary": "Errors are high", "runbook_url": "https://runbooks/errors"}
+}`
+
+func TestValidatePrometheusRule(t *testing.T) {
+	tests := []struct {
+		name string
+		// groups is the JSON of spec.groups, decoded like a request body
+		groups string
+		// want lists the failing fields as group/index/field, the index is -1 for group and object errors
+		want []string
+	}{
+		{
+			name:   "valid alert",
+			groups: `[{"name": "errors", "rules": [` + validAlert + `]}]`,
+		},
+		{
+			name:   "valid recording rule",
+			groups: `[{"name": "records", "rules": [{"record": "job:errors:rate5m", "expr": "sum by (job) (rate(errors_total[5m]))"}]}]`,
+		},
+		{
+			name:   "missing severity, summary and runbook_url",
+			groups: `[{"name": "errors", "rules": [{"alert": "HighErrorRate", "expr": "up == 0"}]}]`,
+			want: []string{
+				"errors/0/annotations.runbook_url",
+				"errors/0/annotations.summary",
+				"errors/0/labels.severity",
+			},
+		},
+		{
+			name:   "bad for and keep_firing_for",
+			groups: `[{"name": "errors", "rules": [` + withFields(validAlert, `"for": "5 minutes", "keep_firing_for": "-1m"`) + `]}]`,
+			want:   []string{"errors/0/for", "errors/0/keep_firing_for"},
+		},
+		{
+			name:   "for on a recording rule",
+			groups: `[{"name": "records", "rules": [{"record": "job:errors:rate5m", "expr": "rate(errors_total[5m])", "for": "5m"}]}]`,
+			want:   []string{"records/0/for"},
+		},
+		{
+			name: "duplicate alert names across groups",
+			groups: `[
+				{"name": "first", "rules": [` + validAlert + `]},
+				{"name": "second", "rules": [` + validAlert + `]}
+			]`,
+			want: []string{"second/0/alert"},
+		},
+		{
+			name:   "duplicate group names",
+			groups: `[{"name": "errors", "rules": [` + validAlert + `]}, {"name": "errors", "rules": [{"record": "job:up", "expr": "up"}]}]`,
+			want:   []string{"errors/-1/name"},
+		},
+		{
+			name:   "numeric expr",
+			groups: `[{"name": "constants", "rules": [{"record": "slo:objective:ratio", "expr": 0.999}]}]`,
+		},
+		{
+			name:   "invalid expr",
+			groups: `[{"name": "errors", "rules": [` + withFields(validAlert, `"expr": "rate(errors_total[5m]"`) + `]}]`,
+			want:   []string{"errors/0/expr"},
+		},
+		{
+			name:   "group without rules",
+			groups: `[{"name": "empty", "rules": []}]`,
+			want:   []string{"empty/-1/rules"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rule map[string]interface{}
+			object := fmt.Sprintf(`{"metadata": {"name": "test"}, "spec": {"groups": %s}}`, tt.groups)
+			if err := json.Unmarshal([]byte(object), &rule); err != nil {
+				t.Fatalf("invalid test object: %v", err)
+			}
+
+			got := validationFields(ValidatePrometheusRule(rule))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidatePrometheusRule() failed fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePrometheusRuleMetadata(t *testing.T) {
+	got := validationFields(ValidatePrometheusRule(map[string]interface{}{"spec": map[string]interface{}{}}))
+	want := []string{"/-1/metadata.name", "/-1/spec.groups"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidatePrometheusRule() failed fields = %v, want %v", got, want)
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule models.Rule
+		want []string
+	}{
+		{
+			name: "neither alert nor record",
+			rule: models.Rule{
+				Expr:        "up",
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": "up", "runbook_url": "https://runbooks/up"},
+			},
+			want: []string{"alert"},
+		},
+		{
+			name: "both alert and record",
+			rule: models.Rule{Alert: "Down", Record: "job:up", Expr: "up == 0"},
+			want: []string{"alert"},
+		},
+		{
+			name: "invalid recording rule name",
+			rule: models.Rule{Record: "job up", Expr: "up"},
+			want: []string{"record"},
+		},
+		{
+			name: "missing expr",
+			rule: models.Rule{Record: "job:up"},
+			want: []string{"expr"},
+		},
+		{
+			name: "annotations on a recording rule",
+			rule: models.Rule{Record: "job:up", Expr: "up", Annotations: map[string]string{"summary": "up"}},
+			want: []string{"annotations"},
+		},
+		{
+			name: "invalid label name",
+			rule: models.Rule{Record: "job:up", Expr: "up", Labels: map[string]string{"team-name": "a"}},
+			want: []string{"labels"},
+		},
+		{
+			name: "keep_firing_for on an alert",
+			rule: models.Rule{
+				Alert: "Down", Expr: "up == 0", KeepFiringFor: "10m",
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": "down", "runbook_url": "https://runbooks/down"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range validateRule(tt.rule) {
+				got = append(got, e.Field)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateRule() failed fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// withFields adds or replaces fields of a JSON object, later keys win when it is decoded
+func withFields(object, fields string) string {
+	return object[:len(object)-1] + ", " + fields + "}"
+}
+
+func validationFields(errs []models.RuleValidationError) []string {
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, fmt.Sprintf("%s/%d/%s", e.Group, e.Index, e.Field))
+	}
+	sort.Strings(fields)
+	return fields
+}
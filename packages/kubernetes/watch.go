@@ -0,0 +1,193 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"main/packages/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// sseHeartbeat keeps idle streams open through proxies that drop silent connections
+const sseHeartbeat = 30 * time.Second
+
+// watchKind opens a watch on one kind. Summarize projects an object for view=summary, it is nil when
+// the kind has no summary view.
+type watchKind struct {
+	Namespaced bool
+	Watch      func(ctx context.Context, c *Cluster, namespace string, opts metav1.ListOptions) (watch.Interface, error)
+	Summarize  func(object interface{}) interface{}
+}
+
+var watchKinds = map[string]watchKind{
+	"pods": {
+		Namespaced: true,
+		Watch: func(ctx context.Context, c *Cluster, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.clientset.CoreV1().Pods(namespace).Watch(ctx, opts)
+		},
+		Summarize: func(object interface{}) interface{} {
+			return SummarizePods([]corev1.Pod{*object.(*corev1.Pod)})[0]
+		},
+	},
+	"deployments": {
+		Namespaced: true,
+		Watch: func(ctx context.Context, c *Cluster, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.clientset.AppsV1().Deployments(namespace).Watch(ctx, opts)
+		},
+		Summarize: func(object interface{}) interface{} {
+			return SummarizeDeployments([]appsv1.Deployment{*object.(*appsv1.Deployment)})[0]
+		},
+	},
+	"nodes": {
+		Watch: func(ctx context.Context, c *Cluster, _ string, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.clientset.CoreV1().Nodes().Watch(ctx, opts)
+		},
+		Summarize: func(object interface{}) interface{} {
+			return SummarizeNodes([]corev1.Node{*object.(*corev1.Node)})[0]
+		},
+	},
+	"prometheusrules": {
+		Namespaced: true,
+		Watch: func(ctx context.Context, c *Cluster, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return c.dynamicClient.Resource(prometheusRuleGVR).Namespace(namespace).Watch(ctx, opts)
+		},
+	},
+}
+
+// GET /watch/{kind}
+// WatchHandler streams the added, modified and deleted objects of a kind as Server-Sent Events. Every event carries
+// the object's resourceVersion as its id, so a reconnecting client resumes through Last-Event-ID (or the
+// resourceVersion parameter). Without one the stream starts with an ADDED event per existing object.
+// When the resourceVersion is too old the stream ends with an error event and the client must restart without it.
+func WatchHandler(w http.ResponseWriter, r *http.Request) {
+	kind, ok := watchKinds[r.PathValue("kind")]
+	if !ok {
+		utils.WriteJSONError(w, fmt.Sprintf("Unknown kind %q, expected pods, deployments, nodes or prometheusrules", r.PathValue("kind")), http.StatusNotFound)
+		return
+	}
+	c, ok := requestCluster(w, r)
+	if !ok {
+		return
+	}
+
+	namespace := namespaceParam(r)
+	if !kind.Namespaced && namespace != "" {
+		utils.WriteJSONError(w, fmt.Sprintf("%s are not namespaced, the namespace parameter does not apply", r.PathValue("kind")), http.StatusBadRequest)
+		return
+	}
+	opts, ok := listOptionsFromRequest(w, r)
+	if !ok {
+		return
+	}
+	opts.Limit, opts.Continue = 0, ""
+	opts.AllowWatchBookmarks = true
+	opts.ResourceVersion = r.Header.Get("Last-Event-ID")
+	if opts.ResourceVersion == "" {
+		opts.ResourceVersion = r.URL.Query().Get("resourceVersion")
+	}
+
+	view, ok := viewFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if view == viewSummary && kind.Summarize == nil {
+		utils.WriteJSONError(w, fmt.Sprintf("%s have no summary view", r.PathValue("kind")), http.StatusBadRequest)
+		return
+	}
+
+	watcher, err := kind.Watch(r.Context(), c, namespace, opts)
+	if err != nil {
+		writeKubernetesError(w, "Failed to watch "+r.PathValue("kind"), err)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear the write deadline of a watch stream: %v", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	controller.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			watcher.Stop()
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			controller.Flush()
+
+		case event, open := <-watcher.ResultChan():
+			if !open {
+				// The API server ends watches after a few minutes; continue from the last delivered version
+				if watcher, err = kind.Watch(r.Context(), c, namespace, opts); err != nil {
+					writeSSEError(w, err)
+					controller.Flush()
+					return
+				}
+				continue
+			}
+
+			if event.Type == watch.Error {
+				writeSSEError(w, apierrors.FromObject(event.Object))
+				controller.Flush()
+				watcher.Stop()
+				return
+			}
+
+			accessor, err := meta.Accessor(event.Object)
+			if err != nil {
+				continue
+			}
+			opts.ResourceVersion = accessor.GetResourceVersion()
+			if event.Type == watch.Bookmark {
+				// Bookmarks only move the resume point forward
+				fmt.Fprintf(w, "id: %s\nevent: BOOKMARK\ndata: {}\n\n", opts.ResourceVersion)
+				controller.Flush()
+				continue
+			}
+
+			var payload interface{} = event.Object
+			if view == viewSummary {
+				payload = kind.Summarize(event.Object)
+			}
+			data, err := json.Marshal(payload)
+			if err != nil {
+				log.Printf("JSON encoding error: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", opts.ResourceVersion, event.Type, data)
+			controller.Flush()
+		}
+	}
+}
+
+// writeSSEError sends an error event carrying the HTTP status of the failure, 410 means the resourceVersion expired
+func writeSSEError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if status, ok := err.(apierrors.APIStatus); ok && status.Status().Code != 0 {
+		code = int(status.Status().Code)
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"error":      err.Error(),
+		"statusCode": code,
+	})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // Alert represents a single alert.
 type Alert struct {
@@ -66,3 +71,548 @@ type AlertmanagerPayload struct {
 	GroupKey          string            `json:"groupKey"`
 	TruncatedAlerts   int               `json:"truncatedAlerts,omitempty"`
 }
+
+// AlertmanagerAlert represents an alert as reported by the Alertmanager v2 API.
+type AlertmanagerAlert struct {
+	Fingerprint  string            `json:"fingerprint"`
+	State        string            `json:"state"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	Receivers    []string          `json:"receivers"`
+	SilencedBy   []string          `json:"silencedBy"`
+	InhibitedBy  []string          `json:"inhibitedBy"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	UpdatedAt    time.Time         `json:"updatedAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// AlertGroup represents a group of alerts routed to a single receiver.
+type AlertGroup struct {
+	Receiver string              `json:"receiver"`
+	Labels   map[string]string   `json:"labels"`
+	Alerts   []AlertmanagerAlert `json:"alerts"`
+}
+
+type Receiver struct {
+	Name string `json:"name"`
+}
+
+type ClusterPeer struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildUser string `json:"buildUser"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// AlertmanagerStatus represents the cluster, version and configuration state of Alertmanager.
+type AlertmanagerStatus struct {
+	Healthy       bool          `json:"healthy"`
+	ClusterName   string        `json:"clusterName"`
+	ClusterStatus string        `json:"clusterStatus"`
+	Peers         []ClusterPeer `json:"peers"`
+	VersionInfo   VersionInfo   `json:"versionInfo"`
+	Uptime        time.Time     `json:"uptime"`
+	Config        string        `json:"config"`
+}
+
+// RouteTestRequest is the label set to evaluate against the Alertmanager routing tree.
+// Config optionally overrides the live configuration with a candidate one.
+type RouteTestRequest struct {
+	Labels map[string]string `json:"labels"`
+	Config string            `json:"config,omitempty"`
+}
+
+// RouteMatch describes a single route of the routing tree that matched a label set.
+type RouteMatch struct {
+	Receiver            string   `json:"receiver"`
+	Path                []string `json:"path"`
+	GroupBy             []string `json:"groupBy"`
+	GroupByAll          bool     `json:"groupByAll"`
+	GroupWait           string   `json:"groupWait"`
+	GroupInterval       string   `json:"groupInterval"`
+	RepeatInterval      string   `json:"repeatInterval"`
+	Continue            bool     `json:"continue"`
+	MuteTimeIntervals   []string `json:"muteTimeIntervals,omitempty"`
+	ActiveTimeIntervals []string `json:"activeTimeIntervals,omitempty"`
+}
+
+type RouteTestResponse struct {
+	Labels    map[string]string `json:"labels"`
+	Receivers []string          `json:"receivers"`
+	Matches   []RouteMatch      `json:"matches"`
+}
+
+// PrometheusRuleSpec is the typed form of the spec of a monitoring.coreos.com/v1 PrometheusRule.
+type PrometheusRuleSpec struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+type RuleGroup struct {
+	Name     string `json:"name"`
+	Interval string `json:"interval,omitempty"`
+	Rules    []Rule `json:"rules"`
+}
+
+// Rule is either an alerting rule (Alert set) or a recording rule (Record set).
+type Rule struct {
+	Record        string            `json:"record,omitempty"`
+	Alert         string            `json:"alert,omitempty"`
+	Expr          string            `json:"expr"`
+	For           string            `json:"for,omitempty"`
+	KeepFiringFor string            `json:"keep_firing_for,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// UnmarshalJSON also accepts a number as expr, which the int-or-string schema of the PrometheusRule CRD allows
+// (expr: 1 is a common always-firing rule). The number is kept as its PromQL literal.
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	type plainRule Rule
+	var decoded struct {
+		plainRule
+		Expr json.RawMessage `json:"expr"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = Rule(decoded.plainRule)
+
+	expr := bytes.TrimSpace(decoded.Expr)
+	switch {
+	case len(expr) == 0, bytes.Equal(expr, []byte("null")):
+		r.Expr = ""
+	case expr[0] == '"':
+		return json.Unmarshal(expr, &r.Expr)
+	default:
+		var number json.Number
+		if err := json.Unmarshal(expr, &number); err != nil {
+			return fmt.Errorf("expr must be a string or a number, got %s", expr)
+		}
+		r.Expr = number.String()
+	}
+	return nil
+}
+
+// RuleValidationError describes a single problem found in a PrometheusRule.
+// Index is the position of the rule within its group, or -1 for problems with the resource or group itself.
+type RuleValidationError struct {
+	Group   string `json:"group,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Index   int    `json:"index"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type RuleValidationResponse struct {
+	Valid  bool                  `json:"valid"`
+	Errors []RuleValidationError `json:"errors"`
+}
+
+// RuleTestRequest runs promtool-style unit tests against either an inline PrometheusRule
+// or an existing one referenced by Name and Namespace.
+type RuleTestRequest struct {
+	Rule               map[string]interface{} `json:"rule,omitempty"`
+	Name               string                 `json:"name,omitempty"`
+	Namespace          string                 `json:"namespace,omitempty"`
+	EvaluationInterval string                 `json:"evaluation_interval,omitempty"`
+	Tests              []RuleTestGroup        `json:"tests"`
+}
+
+// RuleTestGroup mirrors a test group of a promtool rule test file.
+type RuleTestGroup struct {
+	Name            string               `json:"name,omitempty"`
+	Interval        string               `json:"interval,omitempty"`
+	InputSeries     []RuleTestSeries     `json:"input_series"`
+	AlertRuleTests  []AlertRuleTestCase  `json:"alert_rule_test,omitempty"`
+	PromqlExprTests []PromqlExprTestCase `json:"promql_expr_test,omitempty"`
+	ExternalLabels  map[string]string    `json:"external_labels,omitempty"`
+}
+
+// RuleTestSeries is an input series in expanding notation, e.g. "0+10x5 _ 100".
+type RuleTestSeries struct {
+	Series string `json:"series"`
+	Values string `json:"values"`
+}
+
+type AlertRuleTestCase struct {
+	EvalTime  string          `json:"eval_time"`
+	Alertname string          `json:"alertname"`
+	ExpAlerts []ExpectedAlert `json:"exp_alerts"`
+}
+
+type ExpectedAlert struct {
+	ExpLabels      map[string]string `json:"exp_labels"`
+	ExpAnnotations map[string]string `json:"exp_annotations"`
+}
+
+type PromqlExprTestCase struct {
+	Expr       string           `json:"expr"`
+	EvalTime   string           `json:"eval_time"`
+	ExpSamples []ExpectedSample `json:"exp_samples"`
+}
+
+type ExpectedSample struct {
+	Labels string  `json:"labels"`
+	Value  float64 `json:"value"`
+}
+
+type RuleTestResponse struct {
+	Passed  bool                  `json:"passed"`
+	Results []RuleTestGroupResult `json:"results"`
+}
+
+type RuleTestGroupResult struct {
+	Name     string   `json:"name"`
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures"`
+}
+
+// BacktestRequest describes an alert rule to replay against historical Prometheus data.
+type BacktestRequest struct {
+	Alertname string    `json:"alertname,omitempty"`
+	Expr      string    `json:"expr"`
+	For       string    `json:"for,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Step      string    `json:"step,omitempty"`
+	Compare   bool      `json:"compare,omitempty"`
+}
+
+// BacktestInterval is a single would-be alert. FiringAt is nil when the alert never left pending,
+// ResolvedAt is nil when it was still active at the end of the range.
+type BacktestInterval struct {
+	ActiveAt   time.Time  `json:"activeAt"`
+	FiringAt   *time.Time `json:"firingAt,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+type BacktestSeries struct {
+	Labels    map[string]string  `json:"labels"`
+	Firings   int                `json:"firings"`
+	Intervals []BacktestInterval `json:"intervals"`
+}
+
+// BacktestComparison holds the alerts actually stored for the rule during the backtested range.
+type BacktestComparison struct {
+	Stored int             `json:"stored"`
+	Alerts []AlertResponse `json:"alerts"`
+}
+
+type BacktestResponse struct {
+	Expr       string              `json:"expr"`
+	For        string              `json:"for,omitempty"`
+	Start      time.Time           `json:"start"`
+	End        time.Time           `json:"end"`
+	Step       string              `json:"step"`
+	Firings    int                 `json:"firings"`
+	Series     []BacktestSeries    `json:"series"`
+	Comparison *BacktestComparison `json:"comparison,omitempty"`
+}
+
+// FieldChange is a single difference between two objects, Path is a JSON pointer.
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// RuleRevision is a snapshot of a PrometheusRule taken around a create, update, delete or rollback.
+// Previous is the object before the change and Current the object after it, either is nil when it did not exist.
+type RuleRevision struct {
+	Cluster   string                 `json:"cluster"`
+	Namespace string                 `json:"namespace"`
+	Name      string                 `json:"name"`
+	Revision  int                    `json:"revision"`
+	ID        string                 `json:"id"`
+	Action    string                 `json:"action"`
+	Author    string                 `json:"author"`
+	CreatedAt time.Time              `json:"createdAt"`
+	Previous  map[string]interface{} `json:"previous,omitempty"`
+	Current   map[string]interface{} `json:"current,omitempty"`
+	Diff      []FieldChange          `json:"diff"`
+}
+
+// RuleImportResult summarizes the application of a PrometheusRule bundle, entries are "namespace/name".
+type RuleImportResult struct {
+	DryRun    bool                `json:"dryRun"`
+	Created   []string            `json:"created"`
+	Updated   []string            `json:"updated"`
+	Unchanged []string            `json:"unchanged"`
+	Failed    []RuleImportFailure `json:"failed"`
+}
+
+type RuleImportFailure struct {
+	Document int                   `json:"document"`
+	Rule     string                `json:"rule,omitempty"`
+	Error    string                `json:"error"`
+	Errors   []RuleValidationError `json:"errors,omitempty"`
+}
+
+// RuleDryRunResult is returned instead of the written object when a rule change is requested with dryRun=true.
+// Object is the object as the API server would store it, nil for deletions.
+type RuleDryRunResult struct {
+	DryRun bool                   `json:"dryRun"`
+	Object map[string]interface{} `json:"object,omitempty"`
+	Diff   []FieldChange          `json:"diff"`
+}
+
+// RuleTemplate is a named, parameterized set of rule groups. Parameters are referenced in any string
+// of the groups as [[ .name ]], so Prometheus templating like {{ $labels.pod }} is left untouched.
+type RuleTemplate struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Source      string                  `json:"source"`
+	Parameters  []RuleTemplateParameter `json:"parameters,omitempty"`
+	Groups      []RuleGroup             `json:"groups"`
+}
+
+type RuleTemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// RuleTemplateRenderRequest names the PrometheusRule to produce from a template and sets its parameters
+type RuleTemplateRenderRequest struct {
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// SLO declares a service level objective. The agent generates its recording and multi-window
+// multi-burn-rate alerting rules as a PrometheusRule named slo-<name> in the SLO namespace.
+// Objective is a percentage such as 99.9, Window the compliance period, 30d by default.
+type SLO struct {
+	Name           string            `json:"name"`
+	Namespace      string            `json:"namespace"`
+	Service        string            `json:"service"`
+	Description    string            `json:"description,omitempty"`
+	SLI            SLOIndicator      `json:"sli"`
+	Objective      float64           `json:"objective"`
+	Window         string            `json:"window,omitempty"`
+	RunbookURL     string            `json:"runbookUrl"`
+	PageSeverity   string            `json:"pageSeverity,omitempty"`
+	TicketSeverity string            `json:"ticketSeverity,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	RuleName       string            `json:"ruleName,omitempty"`
+}
+
+// SLOIndicator holds the counter selectors of good and total events, e.g. http_requests_total{job="api",code!~"5.."}
+type SLOIndicator struct {
+	Good  string `json:"good"`
+	Total string `json:"total"`
+}
+
+// MonitorValidationError describes a single problem found in a ServiceMonitor or PodMonitor
+type MonitorValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type MonitorValidationResponse struct {
+	Valid  bool                     `json:"valid"`
+	Errors []MonitorValidationError `json:"errors"`
+}
+
+// MonitorCheck explains whether a ServiceMonitor or PodMonitor selects anything and is scraped by Prometheus
+type MonitorCheck struct {
+	Kind            string                `json:"kind"`
+	Namespace       string                `json:"namespace"`
+	Name            string                `json:"name"`
+	Selector        string                `json:"selector"`
+	Namespaces      []string              `json:"namespaces"`
+	Matches         []MonitorMatch        `json:"matches"`
+	ActiveTargets   int                   `json:"activeTargets"`
+	HealthyTargets  int                   `json:"healthyTargets"`
+	Targets         []MonitorTargetStatus `json:"targets"`
+	PrometheusError string                `json:"prometheusError,omitempty"`
+	Healthy         bool                  `json:"healthy"`
+	Problems        []string              `json:"problems"`
+}
+
+// MonitorMatch is a Service or Pod selected by a monitor. MissingPorts lists the endpoint ports it does not expose.
+type MonitorMatch struct {
+	Namespace    string   `json:"namespace"`
+	Name         string   `json:"name"`
+	Ports        []string `json:"ports"`
+	MissingPorts []string `json:"missingPorts,omitempty"`
+}
+
+type MonitorTargetStatus struct {
+	ScrapePool string    `json:"scrapePool"`
+	ScrapeURL  string    `json:"scrapeUrl"`
+	Health     string    `json:"health"`
+	LastError  string    `json:"lastError,omitempty"`
+	LastScrape time.Time `json:"lastScrape"`
+}
+
+// AlertmanagerConfigValidationError describes a single problem found in an AlertmanagerConfig
+type AlertmanagerConfigValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type AlertmanagerConfigValidationResponse struct {
+	Valid  bool                                `json:"valid"`
+	Errors []AlertmanagerConfigValidationError `json:"errors"`
+}
+
+// ClusterInfo describes a cluster registered with the agent
+type ClusterInfo struct {
+	Name    string `json:"name"`
+	Host    string `json:"host"`
+	Source  string `json:"source"`
+	Default bool   `json:"default"`
+}
+
+// ClusterListResponse is a listing aggregated over every cluster with cluster=*.
+// Each item carries a cluster field, clusters that could not be listed are reported in Errors.
+type ClusterListResponse struct {
+	Items  []map[string]interface{} `json:"items"`
+	Errors []ClusterError           `json:"errors"`
+}
+
+type ClusterError struct {
+	Cluster string `json:"cluster"`
+	Error   string `json:"error"`
+}
+
+// ReadinessResponse reports whether the informer caches serving the Kubernetes listings have synced
+type ReadinessResponse struct {
+	Ready    bool                 `json:"ready"`
+	Clusters []ClusterCacheStatus `json:"clusters"`
+}
+
+// ClusterCacheStatus is the sync state per cached kind of one cluster, Cached is false when it reads live
+type ClusterCacheStatus struct {
+	Cluster string          `json:"cluster"`
+	Cached  bool            `json:"cached"`
+	Synced  bool            `json:"synced"`
+	Kinds   map[string]bool `json:"kinds"`
+}
+
+// PodSummary is the compact projection of a pod returned with view=summary
+type PodSummary struct {
+	Name            string        `json:"name"`
+	Namespace       string        `json:"namespace"`
+	Phase           string        `json:"phase"`
+	ReadyContainers int           `json:"readyContainers"`
+	TotalContainers int           `json:"totalContainers"`
+	Restarts        int32         `json:"restarts"`
+	Node            string        `json:"node"`
+	CreatedAt       time.Time     `json:"createdAt"`
+	Age             string        `json:"age"`
+	Owner           *OwnerSummary `json:"owner,omitempty"`
+}
+
+// OwnerSummary names the controller of an object. Pods of a Deployment report the Deployment, not the ReplicaSet.
+type OwnerSummary struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// DeploymentSummary is the compact projection of a deployment returned with view=summary
+type DeploymentSummary struct {
+	Name              string             `json:"name"`
+	Namespace         string             `json:"namespace"`
+	DesiredReplicas   int32              `json:"desiredReplicas"`
+	ReadyReplicas     int32              `json:"readyReplicas"`
+	UpdatedReplicas   int32              `json:"updatedReplicas"`
+	AvailableReplicas int32              `json:"availableReplicas"`
+	Conditions        []ConditionSummary `json:"conditions"`
+	CreatedAt         time.Time          `json:"createdAt"`
+	Age               string             `json:"age"`
+}
+
+// NodeSummary is the compact projection of a node returned with view=summary
+type NodeSummary struct {
+	Name           string             `json:"name"`
+	Roles          []string           `json:"roles"`
+	Conditions     []ConditionSummary `json:"conditions"`
+	Capacity       map[string]string  `json:"capacity"`
+	Allocatable    map[string]string  `json:"allocatable"`
+	KubeletVersion string             `json:"kubeletVersion"`
+	Unschedulable  bool               `json:"unschedulable"`
+	CreatedAt      time.Time          `json:"createdAt"`
+	Age            string             `json:"age"`
+}
+
+type ConditionSummary struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ResourceKind is a kind available through the generic resource endpoints
+type ResourceKind struct {
+	Name         string `json:"name"`
+	Group        string `json:"group"`
+	Namespaced   bool   `json:"namespaced"`
+	MetadataOnly bool   `json:"metadataOnly"`
+}
+
+// EventSummary is the compact projection of a Kubernetes event
+type EventSummary struct {
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count"`
+	Source    string    `json:"source,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// InvolvedObject is a Kubernetes object an alert refers to through its labels
+type InvolvedObject struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// AlertEventsResponse holds the events of the objects referenced by an alert within its time window
+type AlertEventsResponse struct {
+	Fingerprint string           `json:"fingerprint"`
+	AlertName   string           `json:"alert_name"`
+	Cluster     string           `json:"cluster"`
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Objects     []InvolvedObject `json:"objects"`
+	Events      []EventSummary   `json:"events"`
+}
+
+// WorkloadActionRequest is the body of the restart, scale and rollback endpoints; the reason is required
+type WorkloadActionRequest struct {
+	Reason   string `json:"reason"`
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+// WorkloadAction is an audit log entry of an action attempted on a deployment or statefulset. The outcome is
+// succeeded, refused by the checks of this service or failed by the API server, with the error of the latter two.
+type WorkloadAction struct {
+	Cluster   string                 `json:"cluster"`
+	Namespace string                 `json:"namespace"`
+	Kind      string                 `json:"kind"`
+	Name      string                 `json:"name"`
+	Action    string                 `json:"action"`
+	Author    string                 `json:"author"`
+	Reason    string                 `json:"reason"`
+	CreatedAt time.Time              `json:"createdAt"`
+	Outcome   string                 `json:"outcome"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"main/packages/config"
+	"net/http"
+	"strings"
+)
+
+// namespaceAccess maps identities to the namespaces they may change, act on and read pod logs of, configured as
+// NAMESPACE_ACCESS="alice=team-a|team-b,bob=*". Without it every caller may touch every namespace.
+var namespaceAccess = parseNamespaceAccess(config.GetEnv("NAMESPACE_ACCESS", ""))
+
+func parseNamespaceAccess(value string) map[string][]string {
+	access := map[string][]string{}
+	for _, pair := range strings.Split(value, ",") {
+		name, namespaces, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			continue
+		}
+		for _, ns := range strings.Split(namespaces, "|") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				access[name] = append(access[name], ns)
+			}
+		}
+	}
+	return access
+}
+
+// AllowedNamespaces returns the namespaces the caller may touch, or all=true when it is not restricted.
+// Once NAMESPACE_ACCESS is set every identity without an entry gets no namespaces, including the shared
+// token, which needs an explicit default=* to keep full access, and unauthenticated callers.
+func AllowedNamespaces(r *http.Request) (namespaces []string, all bool) {
+	identity := IdentityFromRequest(r)
+	if len(namespaceAccess) == 0 {
+		return nil, true
+	}
+	for _, ns := range namespaceAccess[identity] {
+		if ns == "*" {
+			return nil, true
+		}
+	}
+	return namespaceAccess[identity], false
+}
+
+// CanAccessNamespace reports whether the caller may touch the namespace
+func CanAccessNamespace(r *http.Request, namespace string) bool {
+	namespaces, all := AllowedNamespaces(r)
+	if all {
+		return true
+	}
+	for _, ns := range namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
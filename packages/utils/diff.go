@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"main/packages/models"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffObjects compares two decoded JSON values and returns the changes needed to turn old into new.
+// Paths are JSON pointers, so the result maps directly onto a JSON patch.
+func DiffObjects(old, new interface{}) []models.FieldChange {
+	changes := []models.FieldChange{}
+	diffValues("", old, new, &changes)
+	return changes
+}
+
+func diffValues(path string, old, new interface{}, changes *[]models.FieldChange) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if (oldIsMap || old == nil) && (newIsMap || new == nil) && (oldIsMap || newIsMap) {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+		for key := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := path + "/" + escapePointer(key)
+			oldValue, inOld := oldMap[key]
+			newValue, inNew := newMap[key]
+			switch {
+			case !inOld:
+				*changes = append(*changes, models.FieldChange{Path: childPath, Op: "add", New: newValue})
+			case !inNew:
+				*changes = append(*changes, models.FieldChange{Path: childPath, Op: "remove", Old: oldValue})
+			default:
+				diffValues(childPath, oldValue, newValue, changes)
+			}
+		}
+		return
+	}
+
+	oldSlice, oldIsSlice := old.([]interface{})
+	newSlice, newIsSlice := new.([]interface{})
+	if oldIsSlice && newIsSlice {
+		for i := 0; i < len(oldSlice) && i < len(newSlice); i++ {
+			diffValues(fmt.Sprintf("%s/%d", path, i), oldSlice[i], newSlice[i], changes)
+		}
+		for i := len(oldSlice); i < len(newSlice); i++ {
+			*changes = append(*changes, models.FieldChange{Path: fmt.Sprintf("%s/%d", path, i), Op: "add", New: newSlice[i]})
+		}
+		// Trailing elements are removed from the end, so each index is still valid when the changes are applied in order
+		for i := len(oldSlice) - 1; i >= len(newSlice); i-- {
+			*changes = append(*changes, models.FieldChange{Path: fmt.Sprintf("%s/%d", path, i), Op: "remove", Old: oldSlice[i]})
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, models.FieldChange{Path: path, Op: "replace", Old: old, New: new})
+	}
+}
+
+// escapePointer escapes a key for use as a JSON pointer segment (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"main/packages/models"
+)
+
+func TestDiffObjects(t *testing.T) {
+	tests := []struct {
+		name string
+		// old and new are JSON documents, decoded like stored revisions
+		old, new string
+		want     []models.FieldChange
+	}{
+		{
+			name: "equal objects",
+			old:  `{"spec": {"groups": [{"name": "a"}]}}`,
+			new:  `{"spec": {"groups": [{"name": "a"}]}}`,
+			want: []models.FieldChange{},
+		},
+		{
+			name: "nested maps",
+			old:  `{"metadata": {"labels": {"team": "a", "tier": "web"}}}`,
+			new:  `{"metadata": {"labels": {"team": "b", "tier": "web"}}}`,
+			want: []models.FieldChange{
+				{Path: "/metadata/labels/team", Op: "replace", Old: "a", New: "b"},
+			},
+		},
+		{
+			name: "added and removed keys in key order",
+			old:  `{"a": 1, "c": {"x": true}}`,
+			new:  `{"b": 2, "c": {"y": false}}`,
+			want: []models.FieldChange{
+				{Path: "/a", Op: "remove", Old: float64(1)},
+				{Path: "/b", Op: "add", New: float64(2)},
+				{Path: "/c/x", Op: "remove", Old: true},
+				{Path: "/c/y", Op: "add", New: false},
+			},
+		},
+		{
+			name: "map added where there was none",
+			old:  `{"metadata": {"name": "api"}}`,
+			new:  `{"metadata": {"name": "api", "labels": {"team": "a"}}}`,
+			want: []models.FieldChange{
+				{Path: "/metadata/labels", Op: "add", New: map[string]interface{}{"team": "a"}},
+			},
+		},
+		{
+			name: "longer list",
+			old:  `{"rules": ["a", "b"]}`,
+			new:  `{"rules": ["a", "c", "d", "e"]}`,
+			want: []models.FieldChange{
+				{Path: "/rules/1", Op: "replace", Old: "b", New: "c"},
+				{Path: "/rules/2", Op: "add", New: "d"},
+				{Path: "/rules/3", Op: "add", New: "e"},
+			},
+		},
+		{
+			name: "shorter list removes from the end",
+			old:  `{"rules": [{"alert": "A"}, {"alert": "B"}, {"alert": "C"}, {"alert": "D"}]}`,
+			new:  `{"rules": [{"alert": "A"}, {"alert": "X"}]}`,
+			want: []models.FieldChange{
+				{Path: "/rules/1/alert", Op: "replace", Old: "B", New: "X"},
+				{Path: "/rules/3", Op: "remove", Old: map[string]interface{}{"alert": "D"}},
+				{Path: "/rules/2", Op: "remove", Old: map[string]interface{}{"alert": "C"}},
+			},
+		},
+		{
+			name: "type change",
+			old:  `{"expr": ["up"]}`,
+			new:  `{"expr": "up"}`,
+			want: []models.FieldChange{
+				{Path: "/expr", Op: "replace", Old: []interface{}{"up"}, New: "up"},
+			},
+		},
+		{
+			name: "escaped keys",
+			old:  `{"annotations": {"example.com/owner": "a", "x~y": "1"}}`,
+			new:  `{"annotations": {"example.com/owner": "b", "x~y": "2"}}`,
+			want: []models.FieldChange{
+				{Path: "/annotations/example.com~1owner", Op: "replace", Old: "a", New: "b"},
+				{Path: "/annotations/x~0y", Op: "replace", Old: "1", New: "2"},
+			},
+		},
+		{
+			name: "created object",
+			old:  `null`,
+			new:  `{"kind": "PrometheusRule"}`,
+			want: []models.FieldChange{
+				{Path: "/kind", Op: "add", New: "PrometheusRule"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var old, new interface{}
+			if err := json.Unmarshal([]byte(tt.old), &old); err != nil {
+				t.Fatalf("invalid old document: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.new), &new); err != nil {
+				t.Fatalf("invalid new document: %v", err)
+			}
+
+			if got := DiffObjects(old, new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffObjects() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,20 +1,61 @@
 package utils
 
 import (
+	"context"
+	"main/packages/config"
 	"net/http"
 	"strings"
 )
 
+type identityKey struct{}
+
+// DefaultIdentity is the identity of callers using the shared AUTH_TOKEN
+const DefaultIdentity = "default"
+
+// namedTokens maps per-user tokens to identities, configured as AUTH_TOKENS="alice=token1,bob=token2"
+var namedTokens = parseNamedTokens(config.GetEnv("AUTH_TOKENS", ""))
+
+func parseNamedTokens(value string) map[string]string {
+	tokens := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		name, token, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" && token != "" {
+			tokens[token] = name
+		}
+	}
+	return tokens
+}
+
 // AuthenticationMiddleware checks the Authorization header for a valid token.
+// The shared token authenticates as DefaultIdentity, tokens from AUTH_TOKENS as their user.
 func AuthenticationMiddleware(next http.Handler, token string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if token != "" {
+		identity := "anonymous"
+		if token != "" || len(namedTokens) > 0 {
 			authHeader := r.Header.Get("Authorization")
-			if !strings.HasPrefix(authHeader, "Bearer ") || strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer ")) != token {
+			bearer := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+			user, named := namedTokens[bearer]
+			switch {
+			case !strings.HasPrefix(authHeader, "Bearer "):
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			case token != "" && bearer == token:
+				identity = DefaultIdentity
+			case named:
+				identity = user
+			default:
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
 	})
 }
+
+// IdentityFromRequest returns the identity the request was authenticated as
+func IdentityFromRequest(r *http.Request) string {
+	if identity, ok := r.Context().Value(identityKey{}).(string); ok {
+		return identity
+	}
+	return "anonymous"
+}
@@ -2,17 +2,154 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"main/packages/config"
 	"net/http"
 	"net/url"
-	"main/packages/config"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 )
 
 type PromQLValidationResponse struct {
-	Valid  bool   `json:"valid"`
-	Error  string `json:"error,omitempty"`
-	Query  string `json:"query"`
+	Valid    bool                  `json:"valid"`
+	Error    string                `json:"error,omitempty"`
+	Position *PromQLErrorPosition  `json:"position,omitempty"`
+	Query    string                `json:"query"`
+	Type     string                `json:"type,omitempty"`
+	Metrics  []string              `json:"metrics,omitempty"`
+	Matchers []PromQLLabelMatchers `json:"matchers,omitempty"`
+	Executed bool                  `json:"executed"`
+}
+
+// PromQLErrorPosition locates a parse error inside the query, lines and columns are 1-based
+type PromQLErrorPosition struct {
+	Start  int `json:"start"`
+	End    int `json:"end"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// PromQLLabelMatchers holds the label matchers of a single vector selector
+type PromQLLabelMatchers struct {
+	Selector string   `json:"selector"`
+	Matchers []string `json:"matchers"`
+}
+
+// ValidatePromQL parses a query locally with the Prometheus parser without contacting Prometheus
+func ValidatePromQL(query string) PromQLValidationResponse {
+	response := PromQLValidationResponse{Query: query}
+
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		response.Error = err.Error()
+		var parseErrors parser.ParseErrors
+		if errors.As(err, &parseErrors) && len(parseErrors) > 0 {
+			first := parseErrors[0]
+			response.Error = first.Err.Error()
+			response.Position = newPromQLErrorPosition(query, int(first.PositionRange.Start), int(first.PositionRange.End))
+		}
+		return response
+	}
+
+	response.Valid = true
+	response.Type = string(expr.Type())
+
+	metrics := map[string]struct{}{}
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		selector, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+
+		name := selector.Name
+		matchers := make([]string, 0, len(selector.LabelMatchers))
+		for _, m := range selector.LabelMatchers {
+			if m.Name == labels.MetricName {
+				if name == "" && m.Type == labels.MatchEqual {
+					name = m.Value
+				}
+				if selector.Name != "" {
+					// The metric name matcher is implied by the selector name
+					continue
+				}
+			}
+			matchers = append(matchers, m.String())
+		}
+		if name != "" {
+			metrics[name] = struct{}{}
+		}
+
+		response.Matchers = append(response.Matchers, PromQLLabelMatchers{
+			Selector: selector.String(),
+			Matchers: matchers,
+		})
+		return nil
+	})
+
+	for name := range metrics {
+		response.Metrics = append(response.Metrics, name)
+	}
+	sort.Strings(response.Metrics)
+
+	return response
+}
+
+func newPromQLErrorPosition(query string, start, end int) *PromQLErrorPosition {
+	if start > len(query) {
+		start = len(query)
+	}
+	before := query[:start]
+	return &PromQLErrorPosition{
+		Start:  start,
+		End:    end,
+		Line:   strings.Count(before, "\n") + 1,
+		Column: start - strings.LastIndex(before, "\n"),
+	}
+}
+
+// ExecutePromQL runs the query against the Prometheus instance configured by PROMETHEUS_URL.
+// It returns the error reported by Prometheus, or a non-nil error if Prometheus could not be queried.
+func ExecutePromQL(query string) (string, error) {
+	prometheusUrl := config.GetEnv("PROMETHEUS_URL", "http://localhost:9090")
+
+	validateURL, err := url.Parse(prometheusUrl + "/api/v1/query")
+	if err != nil {
+		return "", fmt.Errorf("invalid Prometheus URL: %v", err)
+	}
+
+	params := url.Values{}
+	params.Add("query", query)
+	validateURL.RawQuery = params.Encode()
+
+	resp, err := http.Get(validateURL.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to query Prometheus: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var prometheusResponse struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType,omitempty"`
+		Error     string `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&prometheusResponse); err != nil {
+		return "", fmt.Errorf("failed to parse Prometheus response: %v", err)
+	}
+
+	if prometheusResponse.Status != "success" {
+		return prometheusResponse.Error, nil
+	}
+	return "", nil
 }
 
+// POST /validate/promql
+// PromQLValidationHandler parses the query locally and, with execute=true, also runs it against Prometheus
 func PromQLValidationHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -21,7 +158,8 @@ func PromQLValidationHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Parse the request body
 	var requestBody struct {
-		Query string `json:"query"`
+		Query   string `json:"query"`
+		Execute bool   `json:"execute"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -33,53 +171,93 @@ func PromQLValidationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get Prometheus URL from environment
-	prometheusUrl := config.GetEnv("PROMETHEUS_URL", "http://localhost:9090")
-	
-	// Create the validation URL
-	validateURL, err := url.Parse(prometheusUrl + "/api/v1/query")
-	if err != nil {
-		http.Error(w, "Invalid Prometheus URL", http.StatusInternalServerError)
-		return
+	response := ValidatePromQL(requestBody.Query)
+
+	if response.Valid && (requestBody.Execute || r.URL.Query().Get("execute") == "true") {
+		queryError, err := ExecutePromQL(requestBody.Query)
+		if err != nil {
+			WriteJSONError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		response.Executed = true
+		if queryError != "" {
+			response.Valid = false
+			response.Error = queryError
+		}
 	}
 
-	// Add query parameters
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PromQLRangeSeries is a single series returned by a Prometheus range query
+type PromQLRangeSeries struct {
+	Metric  map[string]string   `json:"metric"`
+	Samples []PromQLRangeSample `json:"samples"`
+}
+
+type PromQLRangeSample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// QueryRangePromQL runs a range query against the Prometheus instance configured by PROMETHEUS_URL
+func QueryRangePromQL(query string, start, end time.Time, step time.Duration) ([]PromQLRangeSeries, error) {
+	prometheusUrl := config.GetEnv("PROMETHEUS_URL", "http://localhost:9090")
+
 	params := url.Values{}
-	params.Add("query", requestBody.Query)
-	validateURL.RawQuery = params.Encode()
+	params.Add("query", query)
+	params.Add("start", strconv.FormatInt(start.Unix(), 10))
+	params.Add("end", strconv.FormatInt(end.Unix(), 10))
+	params.Add("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
 
-	// Make the request to Prometheus
-	resp, err := http.Get(validateURL.String())
+	resp, err := http.PostForm(prometheusUrl+"/api/v1/query_range", params)
 	if err != nil {
-		http.Error(w, "Failed to validate query", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to query Prometheus: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Parse the response
 	var prometheusResponse struct {
 		Status string `json:"status"`
 		Data   struct {
 			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][2]interface{}  `json:"values"`
+			} `json:"result"`
 		} `json:"data"`
-		ErrorType string `json:"errorType,omitempty"`
-		Error     string `json:"error,omitempty"`
+		Error string `json:"error,omitempty"`
 	}
-
 	if err := json.NewDecoder(resp.Body).Decode(&prometheusResponse); err != nil {
-		http.Error(w, "Failed to parse Prometheus response", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to parse Prometheus response: %v", err)
 	}
-
-	response := PromQLValidationResponse{
-		Query: requestBody.Query,
-		Valid: prometheusResponse.Status == "success",
+	if prometheusResponse.Status != "success" {
+		return nil, fmt.Errorf("range query failed: %s", prometheusResponse.Error)
 	}
 
-	if !response.Valid {
-		response.Error = prometheusResponse.Error
+	series := make([]PromQLRangeSeries, 0, len(prometheusResponse.Data.Result))
+	for _, result := range prometheusResponse.Data.Result {
+		s := PromQLRangeSeries{
+			Metric:  result.Metric,
+			Samples: make([]PromQLRangeSample, 0, len(result.Values)),
+		}
+		for _, value := range result.Values {
+			// Prometheus encodes each sample as [<unix seconds>, "<value>"]
+			ts, ok := value[0].(float64)
+			if !ok {
+				return nil, fmt.Errorf("unexpected sample timestamp %v", value[0])
+			}
+			raw, _ := value[1].(string)
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected sample value %v", value[1])
+			}
+			s.Samples = append(s.Samples, PromQLRangeSample{
+				Time:  time.Unix(0, int64(ts*float64(time.Second))).UTC(),
+				Value: v,
+			})
+		}
+		series = append(series, s)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-} 
\ No newline at end of file
+	return series, nil
+}
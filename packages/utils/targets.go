@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"main/packages/config"
+	"net/http"
+	"time"
+)
+
+// PrometheusTarget is an active scrape target as reported by the Prometheus targets API
+type PrometheusTarget struct {
+	ScrapePool       string            `json:"scrapePool"`
+	ScrapeURL        string            `json:"scrapeUrl"`
+	Health           string            `json:"health"`
+	LastError        string            `json:"lastError,omitempty"`
+	LastScrape       time.Time         `json:"lastScrape"`
+	Labels           map[string]string `json:"labels"`
+	DiscoveredLabels map[string]string `json:"discoveredLabels,omitempty"`
+}
+
+// FetchPrometheusTargets returns the active targets of the Prometheus instance configured by PROMETHEUS_URL
+func FetchPrometheusTargets() ([]PrometheusTarget, error) {
+	prometheusUrl := config.GetEnv("PROMETHEUS_URL", "http://localhost:9090")
+
+	resp, err := http.Get(prometheusUrl + "/api/v1/targets?state=active")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Prometheus: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var prometheusResponse struct {
+		Status string `json:"status"`
+		Data   struct {
+			ActiveTargets []PrometheusTarget `json:"activeTargets"`
+		} `json:"data"`
+		Error string `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&prometheusResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse Prometheus response: %v", err)
+	}
+	if prometheusResponse.Status != "success" {
+		return nil, fmt.Errorf("targets query failed: %s", prometheusResponse.Error)
+	}
+	return prometheusResponse.Data.ActiveTargets, nil
+}